@@ -0,0 +1,212 @@
+// Package astro computes the sun's and moon's positions locally, without
+// any network access. The formulas are NOAA's solar calculator and Meeus'
+// low-precision lunar terms: good to about a minute for sunrise and sunset
+// and a few percent for the moon's illumination, which is plenty for a
+// dashboard.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+// Sun elevations, in degrees, that define the events of a day
+const (
+	HorizonElevation = -0.833 // Upper limb on the horizon, with refraction
+	CivilElevation   = -6.0   // Civil twilight ends, streetlights are needed
+)
+
+// SunDay holds the sun's events on one local calendar day. Events that do
+// not happen that day, as in polar night, are zero.
+type SunDay struct {
+	Sunrise, Sunset time.Time
+	Dawn, Dusk      time.Time     // Start and end of civil twilight; dusk may be after midnight
+	DayLength       time.Duration // Time the sun is up
+	PolarDay        bool          // The sun does not set
+	PolarNight      bool          // The sun does not rise
+}
+
+// Sun returns the events of the local day containing day (in day's
+// location) at the given coordinates, in degrees
+func Sun(day time.Time, lat, lon float64) SunDay {
+	y, m, d := day.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, day.Location())
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, day.Location()) // 23 or 25 hours on DST changes
+
+	var s SunDay
+	rises, sets, up := crossings(start, end, lat, lon, HorizonElevation)
+	if len(rises) > 0 {
+		s.Sunrise = rises[0]
+	}
+	if len(sets) > 0 {
+		s.Sunset = sets[len(sets)-1]
+	}
+	s.DayLength = up
+	s.PolarDay = len(rises) == 0 && len(sets) == 0 && up > 0
+	s.PolarNight = len(rises) == 0 && len(sets) == 0 && up == 0
+
+	// Around midsummer in the south of Finland the evening's twilight ends
+	// after midnight, so dusk is searched for past the end of the day and
+	// the previous evening's dusk is skipped
+	dawns, dusks, _ := crossings(start, end.Add(6*time.Hour), lat, lon, CivilElevation)
+	for _, t := range dawns {
+		if t.Before(end) && (s.Sunrise.IsZero() || !t.After(s.Sunrise)) {
+			s.Dawn = t // The last one before sunrise
+		}
+	}
+	for _, t := range dusks {
+		if s.Sunset.IsZero() {
+			if t.Before(end) {
+				s.Dusk = t
+			}
+		} else if !t.Before(s.Sunset) {
+			s.Dusk = t // The first one after sunset
+			break
+		}
+	}
+	return s
+}
+
+// IsDaylight reports whether the sun is up at t
+func IsDaylight(t time.Time, lat, lon float64) bool {
+	return Elevation(t, lat, lon) > HorizonElevation
+}
+
+// step is the sampling interval when searching for crossings. The sun
+// cannot cross a threshold twice within it outside the polar circles'
+// edge cases, where a missed grazing crossing does not matter.
+const step = 10 * time.Minute
+
+// crossings finds when the sun's elevation rises above and sinks below
+// threshold between start and end, and how long it stays above
+func crossings(start, end time.Time, lat, lon, threshold float64) (rises, sets []time.Time, above time.Duration) {
+	prevT := start
+	prevUp := Elevation(start, lat, lon) > threshold
+	for t := start.Add(step); !prevT.Equal(end); t = t.Add(step) {
+		if t.After(end) {
+			t = end
+		}
+		up := Elevation(t, lat, lon) > threshold
+		switch {
+		case up && !prevUp:
+			at := bisect(prevT, t, lat, lon, threshold)
+			rises = append(rises, at)
+			above += t.Sub(at)
+		case !up && prevUp:
+			at := bisect(prevT, t, lat, lon, threshold)
+			sets = append(sets, at)
+			above += at.Sub(prevT)
+		case up:
+			above += t.Sub(prevT)
+		}
+		prevT, prevUp = t, up
+	}
+	return rises, sets, above
+}
+
+// bisect narrows a crossing of threshold between a and b to a second
+func bisect(a, b time.Time, lat, lon, threshold float64) time.Time {
+	upAtA := Elevation(a, lat, lon) > threshold
+	for b.Sub(a) > time.Second {
+		mid := a.Add(b.Sub(a) / 2)
+		if (Elevation(mid, lat, lon) > threshold) == upAtA {
+			a = mid
+		} else {
+			b = mid
+		}
+	}
+	return b.Truncate(time.Second)
+}
+
+// Elevation returns the sun's elevation above the horizon at t, in degrees,
+// without atmospheric refraction
+func Elevation(t time.Time, lat, lon float64) float64 {
+	jc := julianCentury(t)
+
+	// Geometric mean longitude and anomaly of the sun, and Earth's orbit
+	l0 := math.Mod(280.46646+jc*(36000.76983+jc*0.0003032), 360)
+	m := 357.52911 + jc*(35999.05029-0.0001537*jc)
+	e := 0.016708634 - jc*(0.000042037+0.0000001267*jc)
+
+	center := sin(m)*(1.914602-jc*(0.004817+0.000014*jc)) + sin(2*m)*(0.019993-0.000101*jc) + sin(3*m)*0.000289
+	omega := 125.04 - 1934.136*jc
+	apparentLong := l0 + center - 0.00569 - 0.00478*sin(omega)
+	obliquity := 23 + (26+(21.448-jc*(46.815+jc*(0.00059-jc*0.001813)))/60)/60 + 0.00256*cos(omega)
+	declination := deg(math.Asin(sin(obliquity) * sin(apparentLong)))
+
+	// Equation of time, in minutes
+	y := math.Pow(math.Tan(rad(obliquity/2)), 2)
+	eqTime := 4 * deg(y*sin(2*l0)-2*e*sin(m)+4*e*y*sin(m)*cos(2*l0)-0.5*y*y*sin(4*l0)-1.25*e*e*sin(2*m))
+
+	utc := t.UTC()
+	minutes := float64(utc.Hour()*60+utc.Minute()) + float64(utc.Second())/60
+	solarTime := math.Mod(minutes+eqTime+4*lon, 1440)
+	hourAngle := solarTime/4 - 180
+
+	cosZenith := sin(lat)*sin(declination) + cos(lat)*cos(declination)*cos(hourAngle)
+	return 90 - deg(math.Acos(math.Max(-1, math.Min(1, cosZenith))))
+}
+
+// Moon phase names, by the moon's age in the lunar month
+const (
+	PhaseNew            = "new"
+	PhaseWaxingCrescent = "waxing-crescent"
+	PhaseFirstQuarter   = "first-quarter"
+	PhaseWaxingGibbous  = "waxing-gibbous"
+	PhaseFull           = "full"
+	PhaseWaningGibbous  = "waning-gibbous"
+	PhaseLastQuarter    = "last-quarter"
+	PhaseWaningCrescent = "waning-crescent"
+)
+
+var phaseNames = []string{
+	PhaseNew, PhaseWaxingCrescent, PhaseFirstQuarter, PhaseWaxingGibbous,
+	PhaseFull, PhaseWaningGibbous, PhaseLastQuarter, PhaseWaningCrescent,
+}
+
+// Moon describes the moon as seen from Earth
+type Moon struct {
+	Phase        float64 // Position in the lunar month: 0 new, 0.25 first quarter, 0.5 full, 0.75 last quarter
+	Illumination float64 // Illuminated fraction of the disc, 0-1
+	Name         string  // One of the Phase constants
+}
+
+// MoonAt returns the moon's phase at t
+func MoonAt(t time.Time) Moon {
+	jc := julianCentury(t)
+
+	// Mean elongation of the moon from the sun, and the sun's and moon's
+	// mean anomalies
+	elongation := math.Mod(297.8501921+445267.1114034*jc, 360)
+	if elongation < 0 {
+		elongation += 360
+	}
+	sunAnomaly := 357.5291092 + 35999.0502909*jc
+	moonAnomaly := 134.9633964 + 477198.8675055*jc
+
+	phaseAngle := 180 - elongation -
+		6.289*sin(moonAnomaly) +
+		2.100*sin(sunAnomaly) -
+		1.274*sin(2*elongation-moonAnomaly) -
+		0.658*sin(2*elongation) -
+		0.214*sin(2*moonAnomaly) -
+		0.110*sin(elongation)
+
+	phase := elongation / 360
+	return Moon{
+		Phase:        phase,
+		Illumination: (1 + cos(phaseAngle)) / 2,
+		Name:         phaseNames[int(math.Floor(phase*8+0.5))%8],
+	}
+}
+
+// julianCentury returns the time since J2000.0 in Julian centuries
+func julianCentury(t time.Time) float64 {
+	jd := float64(t.Unix())/86400 + 2440587.5
+	return (jd - 2451545) / 36525
+}
+
+func rad(d float64) float64 { return d * math.Pi / 180 }
+func deg(r float64) float64 { return r * 180 / math.Pi }
+func sin(d float64) float64 { return math.Sin(rad(d)) }
+func cos(d float64) float64 { return math.Cos(rad(d)) }
@@ -0,0 +1,133 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var (
+	eet  = time.FixedZone("EET", 2*3600)
+	eest = time.FixedZone("EEST", 3*3600)
+	bst  = time.FixedZone("BST", 3600)
+)
+
+func TestSun(t *testing.T) {
+	const tolerance = 3 * time.Minute
+
+	// Reference times from published almanacs, to the minute
+	tests := []struct {
+		name            string
+		day             time.Time
+		lat, lon        float64
+		sunrise, sunset time.Time
+	}{
+		{
+			name: "Helsinki midsummer",
+			day:  time.Date(2024, 6, 20, 12, 0, 0, 0, eest),
+			lat:  60.1699, lon: 24.9384,
+			sunrise: time.Date(2024, 6, 20, 3, 54, 0, 0, eest),
+			sunset:  time.Date(2024, 6, 20, 22, 50, 0, 0, eest),
+		},
+		{
+			name: "Helsinki midwinter",
+			day:  time.Date(2024, 12, 21, 12, 0, 0, 0, eet),
+			lat:  60.1699, lon: 24.9384,
+			sunrise: time.Date(2024, 12, 21, 9, 23, 0, 0, eet),
+			sunset:  time.Date(2024, 12, 21, 15, 13, 0, 0, eet),
+		},
+		{
+			name: "London, west of Greenwich",
+			day:  time.Date(2025, 6, 21, 12, 0, 0, 0, bst),
+			lat:  51.5074, lon: -0.1278,
+			sunrise: time.Date(2025, 6, 21, 4, 43, 0, 0, bst),
+			sunset:  time.Date(2025, 6, 21, 21, 21, 0, 0, bst),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Sun(tt.day, tt.lat, tt.lon)
+			if d := s.Sunrise.Sub(tt.sunrise).Abs(); d > tolerance {
+				t.Errorf("sunrise = %s, want %s", s.Sunrise.In(tt.day.Location()).Format("15:04:05"), tt.sunrise.Format("15:04"))
+			}
+			if d := s.Sunset.Sub(tt.sunset).Abs(); d > tolerance {
+				t.Errorf("sunset = %s, want %s", s.Sunset.In(tt.day.Location()).Format("15:04:05"), tt.sunset.Format("15:04"))
+			}
+			if d := (s.DayLength - tt.sunset.Sub(tt.sunrise)).Abs(); d > 2*tolerance {
+				t.Errorf("day length = %s, want %s", s.DayLength, tt.sunset.Sub(tt.sunrise))
+			}
+			if !s.Dawn.Before(s.Sunrise) || !s.Dusk.After(s.Sunset) {
+				t.Errorf("civil twilight %s-%s should surround the day", s.Dawn, s.Dusk)
+			}
+			if s.PolarDay || s.PolarNight {
+				t.Errorf("polar day %v, polar night %v, want neither", s.PolarDay, s.PolarNight)
+			}
+		})
+	}
+}
+
+func TestSunPolar(t *testing.T) {
+	const lat, lon = 69.9078, 27.0282 // Utsjoki
+
+	tests := []struct {
+		name                 string
+		day                  time.Time
+		polarDay, polarNight bool
+		dayLength            time.Duration
+	}{
+		{name: "midnight sun", day: time.Date(2025, 6, 21, 12, 0, 0, 0, eest), polarDay: true, dayLength: 24 * time.Hour},
+		{name: "polar night", day: time.Date(2025, 12, 21, 12, 0, 0, 0, eet), polarNight: true, dayLength: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Sun(tt.day, lat, lon)
+			if s.PolarDay != tt.polarDay || s.PolarNight != tt.polarNight {
+				t.Errorf("polar day %v, polar night %v, want %v, %v", s.PolarDay, s.PolarNight, tt.polarDay, tt.polarNight)
+			}
+			if !s.Sunrise.IsZero() || !s.Sunset.IsZero() {
+				t.Errorf("sunrise %s, sunset %s, want none", s.Sunrise, s.Sunset)
+			}
+			if s.DayLength != tt.dayLength {
+				t.Errorf("day length = %s, want %s", s.DayLength, tt.dayLength)
+			}
+			if got := IsDaylight(tt.day, lat, lon); got != tt.polarDay {
+				t.Errorf("IsDaylight at noon = %v, want %v", got, tt.polarDay)
+			}
+		})
+	}
+}
+
+func TestMoonAt(t *testing.T) {
+	// Principal phases of January 2025, UTC
+	tests := []struct {
+		name         string
+		at           time.Time
+		phase        float64
+		illumination float64
+		want         string
+	}{
+		{name: "first quarter", at: time.Date(2025, 1, 6, 23, 56, 0, 0, time.UTC), phase: 0.25, illumination: 0.5, want: PhaseFirstQuarter},
+		{name: "full", at: time.Date(2025, 1, 13, 22, 27, 0, 0, time.UTC), phase: 0.5, illumination: 1, want: PhaseFull},
+		{name: "last quarter", at: time.Date(2025, 1, 21, 20, 31, 0, 0, time.UTC), phase: 0.75, illumination: 0.5, want: PhaseLastQuarter},
+		{name: "new", at: time.Date(2025, 1, 29, 12, 36, 0, 0, time.UTC), phase: 0, illumination: 0, want: PhaseNew},
+		{name: "waxing crescent", at: time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC), phase: 0.1, illumination: 0.1, want: PhaseWaxingCrescent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := MoonAt(tt.at)
+			if m.Name != tt.want {
+				t.Errorf("name = %q, want %q", m.Name, tt.want)
+			}
+			// New moon may land just below 1 instead of at 0
+			if d := math.Abs(m.Phase - tt.phase); math.Min(d, 1-d) > 0.03 {
+				t.Errorf("phase = %.3f, want %.2f", m.Phase, tt.phase)
+			}
+			if math.Abs(m.Illumination-tt.illumination) > 0.05 {
+				t.Errorf("illumination = %.3f, want %.2f", m.Illumination, tt.illumination)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+// Command mockapis serves synthetic Digitransit, FMI and spot-hinta.fi APIs
+// for developing the dashboard offline. Start it, then run the dashboard
+// with the printed flags.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"rasp_info/config"
+	"rasp_info/mockapi"
+	"sort"
+	"strings"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8090", "Address to listen on")
+	scenario := flag.String("scenario", "normal", "Comma-separated scenarios: "+scenarioNames())
+	delay := flag.Duration("delay", 3*time.Second, "Response delay in the slow scenario")
+	failRate := flag.Float64("fail-rate", 0, "Fraction of requests answered with 503, e.g. 0.2")
+	flag.Parse()
+
+	srv, err := mockapi.New(*scenario, *delay, *failRate)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	base := "http://" + *addr
+	var args []string
+	for name, url := range mockapi.URLs(base) {
+		args = append(args, fmt.Sprintf("-%s=%s", config.FlagName(name), url))
+	}
+	sort.Strings(args)
+	fmt.Printf("Mock APIs on %s (scenario %s). Run the dashboard with:\n\n  go run . %s\n\n", base, *scenario, strings.Join(args, " "))
+	fmt.Printf("Switch scenarios with %s/scenario?set=empty,slow\n", base)
+
+	log.Fatal(http.ListenAndServe(*addr, srv.Handler()))
+}
+
+func scenarioNames() string {
+	var names []string
+	for name := range mockapi.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
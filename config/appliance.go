@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PriceSlot is the length of one spot price period
+const PriceSlot = 15 * time.Minute
+
+// MaxApplianceDuration is the longest run time a cheapest window is looked
+// for; prices are only known about a day ahead
+const MaxApplianceDuration = 24 * time.Hour
+
+// Appliance is something that runs for a fixed time and can be timed to
+// the cheapest electricity, e.g. a dishwasher
+type Appliance struct {
+	Name     string   `json:"name"`
+	Duration Duration `json:"duration"` // Run time, a multiple of 15 minutes
+}
+
+func (a Appliance) validate() error {
+	var errs []error
+	if a.Name == "" {
+		errs = append(errs, errors.New("name must not be empty"))
+	}
+	if err := CheckWindowDuration(a.Duration.Duration); err != nil {
+		errs = append(errs, fmt.Errorf("duration: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// CheckWindowDuration reports whether d can be searched for as a cheapest
+// price window
+func CheckWindowDuration(d time.Duration) error {
+	if d < PriceSlot || d > MaxApplianceDuration {
+		return fmt.Errorf("must be between %s and %s, got %s", PriceSlot, MaxApplianceDuration, d)
+	}
+	if d%PriceSlot != 0 {
+		return fmt.Errorf("must be a multiple of %s, got %s", PriceSlot, d)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"slices"
+	"strings"
+)
+
+// Departure counts per stop
+const (
+	DefaultDepartures = 4
+	MaxDepartures     = 20
+)
+
+// DepartureCount returns how many departures to show for the stop
+func (s BusStop) DepartureCount() int {
+	if s.Departures > 0 {
+		return s.Departures
+	}
+	return DefaultDepartures
+}
+
+// HasFilters reports whether any route or headsign filter is set
+func (s BusStop) HasFilters() bool {
+	return len(s.Routes) > 0 || len(s.ExcludeRoutes) > 0 ||
+		len(s.Headsigns) > 0 || len(s.ExcludeHeadsigns) > 0
+}
+
+// WantsRoute reports whether departures of a route pass the route filters
+func (s BusStop) WantsRoute(route string) bool {
+	if len(s.Routes) > 0 && !slices.Contains(s.Routes, route) {
+		return false
+	}
+	return !slices.Contains(s.ExcludeRoutes, route)
+}
+
+// Wants reports whether a departure passes all filters
+func (s BusStop) Wants(route, headsign string) bool {
+	if !s.WantsRoute(route) {
+		return false
+	}
+	if len(s.Headsigns) > 0 && !containsAnyFold(headsign, s.Headsigns) {
+		return false
+	}
+	return !containsAnyFold(headsign, s.ExcludeHeadsigns)
+}
+
+func containsAnyFold(s string, subs []string) bool {
+	s = strings.ToLower(s)
+	for _, sub := range subs {
+		if strings.Contains(s, strings.ToLower(sub)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package config
+
+import "testing"
+
+func TestBusStopWants(t *testing.T) {
+	tests := []struct {
+		name     string
+		stop     BusStop
+		route    string
+		headsign string
+		want     bool
+	}{
+		{name: "no filters", route: "110", headsign: "Matinkylä", want: true},
+		{name: "listed route", stop: BusStop{Routes: []string{"110", "114"}}, route: "114", headsign: "Kamppi", want: true},
+		{name: "unlisted route", stop: BusStop{Routes: []string{"110", "114"}}, route: "11", headsign: "Kamppi"},
+		{name: "route names match exactly", stop: BusStop{Routes: []string{"110"}}, route: "110T", headsign: "Kamppi"},
+		{name: "excluded route", stop: BusStop{ExcludeRoutes: []string{"N12"}}, route: "N12", headsign: "Kamppi"},
+		{name: "other route not excluded", stop: BusStop{ExcludeRoutes: []string{"N12"}}, route: "12", headsign: "Kamppi", want: true},
+		{name: "headsign substring, any case", stop: BusStop{Headsigns: []string{"matinkylä"}}, route: "110", headsign: "Matinkylä (M)", want: true},
+		{name: "other headsign", stop: BusStop{Headsigns: []string{"Matinkylä"}}, route: "110", headsign: "Kamppi"},
+		{name: "excluded headsign", stop: BusStop{ExcludeHeadsigns: []string{"kamppi"}}, route: "110", headsign: "Kamppi via Lauttasaari"},
+		{name: "exclusion beats inclusion", stop: BusStop{Headsigns: []string{"Kamppi"}, ExcludeHeadsigns: []string{"Lauttasaari"}}, route: "110", headsign: "Kamppi via Lauttasaari"},
+		{name: "route and headsign both pass", stop: BusStop{Routes: []string{"110"}, Headsigns: []string{"Kamppi"}}, route: "110", headsign: "Kamppi", want: true},
+		{name: "headsign passes, route does not", stop: BusStop{Routes: []string{"110"}, Headsigns: []string{"Kamppi"}}, route: "114", headsign: "Kamppi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stop.Wants(tt.route, tt.headsign); got != tt.want {
+				t.Errorf("Wants(%q, %q) = %v, want %v", tt.route, tt.headsign, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,65 +1,190 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
-	"log"
-	"os"
+	"fmt"
 	"strings"
 	"time"
 )
 
+// DefaultPath is the config file read at startup, relative to the working directory
+const DefaultPath = "config.json"
+
+// MaxHTTPRetries bounds http_retries so a failing API can't stall a fetch
+const MaxHTTPRetries = 5
+
 // Config holds all configuration for the application
 type Config struct {
-	Port string `json:"port"`
+	Port string `json:"port" env:"INFOBOARD_PORT"`
+
+	// Fetch Intervals, e.g. "90s" or "15m"
+	WeatherInterval     Duration `json:"weather_interval" env:"INFOBOARD_WEATHER_INTERVAL"`
+	TransportInterval   Duration `json:"transport_interval" env:"INFOBOARD_TRANSPORT_INTERVAL"`
+	ElectricityInterval Duration `json:"electricity_interval" env:"INFOBOARD_ELECTRICITY_INTERVAL"`
+	PlannerInterval     Duration `json:"planner_interval" env:"INFOBOARD_PLANNER_INTERVAL"`
+	BikeInterval        Duration `json:"bike_interval" env:"INFOBOARD_BIKE_INTERVAL"`
+	WarningsInterval    Duration `json:"warnings_interval" env:"INFOBOARD_WARNINGS_INTERVAL"`
 
-	// Fetch Intervals
-	WeatherInterval     time.Duration `json:"-"`
-	TransportInterval   time.Duration `json:"-"`
-	ElectricityInterval time.Duration `json:"-"`
+	// Optional time-of-day overrides of the intervals above
+	WeatherSchedule     []ScheduleRule `json:"weather_schedule,omitempty" env:"INFOBOARD_WEATHER_SCHEDULE"`
+	TransportSchedule   []ScheduleRule `json:"transport_schedule,omitempty" env:"INFOBOARD_TRANSPORT_SCHEDULE"`
+	ElectricitySchedule []ScheduleRule `json:"electricity_schedule,omitempty" env:"INFOBOARD_ELECTRICITY_SCHEDULE"`
 
 	// API Keys and URLs
-	HSLAPIUrl  string `json:"hsl_api_url"`
-	HSLKey     string `json:"hsl_api_key"`
-	FMIAPIUrl  string `json:"fmi_api_url"`
-	SpotAPIUrl string `json:"spot_api_url"`
+	HSLAPIUrl       string `json:"hsl_api_url" env:"INFOBOARD_HSL_API_URL"`
+	HSLKey          string `json:"hsl_api_key" env:"INFOBOARD_HSL_KEY" secret:"true"`
+	GeocodingAPIUrl string `json:"geocoding_api_url" env:"INFOBOARD_GEOCODING_API_URL"` // Resolves stop short codes
+	FMIAPIUrl       string `json:"fmi_api_url" env:"INFOBOARD_FMI_API_URL"`
+	SpotAPIUrl      string `json:"spot_api_url" env:"INFOBOARD_SPOT_API_URL"`
+	WarningsAPIUrl  string `json:"warnings_api_url" env:"INFOBOARD_WARNINGS_API_URL"` // FMI CAP Atom feed
+
+	// HTTP behaviour shared by all fetchers
+	HTTPTimeout Duration `json:"http_timeout" env:"INFOBOARD_HTTP_TIMEOUT"` // Per attempt
+	HTTPRetries int      `json:"http_retries" env:"INFOBOARD_HTTP_RETRIES"` // Retries on 5xx, 429 and network errors
 
 	// User Settings
-	WeatherLocation string    `json:"weather_location"`
-	BusStops        []BusStop `json:"bus_stops"`
+	WeatherLocation  string         `json:"weather_location" env:"INFOBOARD_WEATHER_LOCATION"`
+	WeatherStation   string         `json:"weather_station,omitempty" env:"INFOBOARD_WEATHER_STATION"`     // Observation station: fmisid or place, default weather_location
+	WeatherLocations []WeatherPlace `json:"weather_locations,omitempty" env:"INFOBOARD_WEATHER_LOCATIONS"` // Replaces the two above when set
+	BusStops         []BusStop      `json:"bus_stops" env:"INFOBOARD_BUS_STOPS"`
+	Trips            []Trip         `json:"trips,omitempty" env:"INFOBOARD_TRIPS"` // Saved journeys for the planner
+
+	// Weather warnings are shown for these areas (FMI area names, e.g.
+	// "Uusimaa" or "Espoo"). Without any, warnings covering a weather
+	// location are shown.
+	WarningRegions []string `json:"warning_regions,omitempty" env:"INFOBOARD_WARNING_REGIONS"`
+
+	// The cheapest contiguous electricity window is found for each appliance
+	Appliances []Appliance `json:"appliances,omitempty" env:"INFOBOARD_APPLIANCES"`
+
+	// City bikes. Stations are only polled during the season ("MM-DD", inclusive).
+	BikeStations    []BikeStation `json:"bike_stations,omitempty" env:"INFOBOARD_BIKE_STATIONS"`
+	BikeSeasonStart string        `json:"bike_season_start" env:"INFOBOARD_BIKE_SEASON_START"`
+	BikeSeasonEnd   string        `json:"bike_season_end" env:"INFOBOARD_BIKE_SEASON_END"`
+
+	// Persistence (empty paths disable saving to disk)
+	SnapshotPath  string   `json:"snapshot_path" env:"INFOBOARD_SNAPSHOT_PATH"`
+	StopCachePath string   `json:"stop_cache_path" env:"INFOBOARD_STOP_CACHE_PATH"`
+	StopCacheTTL  Duration `json:"stop_cache_ttl" env:"INFOBOARD_STOP_CACHE_TTL"` // How long a resolved stop code is trusted
+}
+
+type BikeStation struct {
+	ID   string `json:"id"` // Digitransit station id, e.g. "smoove:070"
+	Name string `json:"name,omitempty"`
 }
 
 type BusStop struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+
+	// Optional filters. Route names match exactly, headsigns match
+	// case-insensitively by substring (e.g. "Kamppi").
+	Routes           []string `json:"routes,omitempty"`            // Show only these routes
+	ExcludeRoutes    []string `json:"exclude_routes,omitempty"`    // Never show these routes
+	Headsigns        []string `json:"headsigns,omitempty"`         // Show only departures towards these
+	ExcludeHeadsigns []string `json:"exclude_headsigns,omitempty"` // Never show departures towards these
+
+	Departures  int      `json:"departures,omitempty"`   // How many to show, default 4
+	WalkingTime Duration `json:"walking_time,omitempty"` // Hide departures leaving sooner than this
 }
 
-// Load returns a configuration, reading from config.json if available
-func Load() *Config {
-	cfg := &Config{
+// Default returns the built-in configuration used before any file is applied
+func Default() *Config {
+	return &Config{
 		Port:                ":8080",
-		WeatherInterval:     15 * time.Minute,
-		TransportInterval:   5 * time.Minute,
-		ElectricityInterval: 15 * time.Minute,
+		WeatherInterval:     Duration{15 * time.Minute},
+		TransportInterval:   Duration{5 * time.Minute},
+		ElectricityInterval: Duration{15 * time.Minute},
+		PlannerInterval:     Duration{5 * time.Minute},
+		BikeInterval:        Duration{2 * time.Minute},
+		WarningsInterval:    Duration{15 * time.Minute},
 		HSLAPIUrl:           "https://api.digitransit.fi/routing/v2/hsl/gtfs/v1",
+		GeocodingAPIUrl:     "https://api.digitransit.fi/geocoding/v1/search",
 		FMIAPIUrl:           "https://opendata.fmi.fi/wfs",
 		SpotAPIUrl:          "https://api.spot-hinta.fi/TodayAndDayForward?region=FI&priceResolution=15",
+		WarningsAPIUrl:      "https://alerts.fmi.fi/cap/feed/atom_en-GB.xml",
+		HTTPTimeout:         Duration{10 * time.Second},
+		HTTPRetries:         2,
 		WeatherLocation:     "Espoo",     // Default
 		BusStops:            []BusStop{}, // No defaults - user must configure
+		BikeSeasonStart:     "04-01",     // HSL city bike season
+		BikeSeasonEnd:       "10-31",
+		SnapshotPath:        "snapshot.json",
+		StopCachePath:       "stop_cache.json",
+		StopCacheTTL:        Duration{7 * 24 * time.Hour},
 	}
+}
 
-	// Try loading from config.json
-	if data, err := os.ReadFile("config.json"); err == nil {
-		if err := json.Unmarshal(data, cfg); err != nil {
-			log.Printf("Error parsing config.json: %v", err)
-		}
-	} else {
-		// Fallback to secrets.txt for HSL key if config.json not found
-		if data, err := os.ReadFile("secrets.txt"); err == nil {
-			cfg.HSLKey = strings.TrimSpace(string(data))
-		} else {
-			log.Println("Warning: Could not read config.json or secrets.txt")
+// PlannerTiming returns the journey planner interval
+func (c *Config) PlannerTiming() Schedule {
+	return Schedule{Base: c.PlannerInterval.Duration}
+}
+
+// WarningsTiming returns the weather warnings interval
+func (c *Config) WarningsTiming() Schedule {
+	return Schedule{Base: c.WarningsInterval.Duration}
+}
+
+// BikeTiming returns the city bike interval, paused outside the season
+func (c *Config) BikeTiming() SeasonalSchedule {
+	return SeasonalSchedule{
+		Schedule: Schedule{Base: c.BikeInterval.Duration},
+		Start:    c.BikeSeasonStart,
+		End:      c.BikeSeasonEnd,
+	}
+}
+
+// InBikeSeason reports whether city bike stations are open on t's date
+func (c *Config) InBikeSeason(t time.Time) bool {
+	return inSeason(t, c.BikeSeasonStart, c.BikeSeasonEnd)
+}
+
+// WeatherTiming returns the weather interval combined with its schedule
+func (c *Config) WeatherTiming() Schedule {
+	return Schedule{Base: c.WeatherInterval.Duration, Rules: c.WeatherSchedule}
+}
+
+// TransportTiming returns the transport interval combined with its schedule
+func (c *Config) TransportTiming() Schedule {
+	return Schedule{Base: c.TransportInterval.Duration, Rules: c.TransportSchedule}
+}
+
+// ElectricityTiming returns the electricity interval combined with its schedule
+func (c *Config) ElectricityTiming() Schedule {
+	return Schedule{Base: c.ElectricityInterval.Duration, Rules: c.ElectricitySchedule}
+}
+
+// StopCodes returns the configured stop ids that need resolving (short
+// codes, not full GTFS ids)
+func (c *Config) StopCodes() []string {
+	var codes []string
+	for _, s := range c.BusStops {
+		if !strings.HasPrefix(s.ID, "HSL:") {
+			codes = append(codes, s.ID)
 		}
 	}
+	return codes
+}
 
-	return cfg
+// Load returns a validated configuration built from defaults, secrets.txt
+// and the config file at path. Use a Loader to also apply environment
+// variables and flags.
+func Load(path string) (*Config, error) {
+	cfg, _, err := (&Loader{Path: path}).Load()
+	return cfg, err
+}
+
+// decodeStrict unmarshals JSON into cfg, rejecting unknown fields so typos
+// in config.json are reported instead of silently ignored
+func decodeStrict(data []byte, cfg *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after top-level object")
+	}
+	return nil
 }
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Configuration layers, lowest precedence first
+const (
+	LayerDefault = "default"
+	LayerSecrets = "secrets.txt"
+	LayerFile    = "file"
+	LayerEnv     = "env"
+	LayerFlag    = "flag"
+)
+
+// SecretsPath is the legacy file holding just the HSL API key
+const SecretsPath = "secrets.txt"
+
+const masked = "***MASKED***"
+
+// Origins maps a config field (by JSON name) to the layer that set it
+type Origins map[string]string
+
+// Loader builds a Config from layered sources: defaults, then secrets.txt
+// (only if the HSL key is still empty), then the config file, then
+// environment variables, then command line flags.
+type Loader struct {
+	Path  string
+	Env   func(string) (string, bool) // Defaults to os.LookupEnv
+	flags map[string]string           // JSON field name -> raw flag value
+}
+
+// field describes one overridable Config field
+type field struct {
+	index  int
+	name   string // JSON name, also used for the flag (with dashes)
+	env    string
+	secret bool
+}
+
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		out = append(out, field{
+			index:  i,
+			name:   name,
+			env:    f.Tag.Get("env"),
+			secret: f.Tag.Get("secret") == "true",
+		})
+	}
+	return out
+}
+
+// FlagName returns the command line flag for a JSON field name, e.g.
+// hsl_api_key -> hsl-api-key
+func FlagName(jsonName string) string {
+	return strings.ReplaceAll(jsonName, "_", "-")
+}
+
+// BindFlags registers one flag per config field on fs. Values are applied
+// on every Load, so they survive config file reloads.
+func (l *Loader) BindFlags(fs *flag.FlagSet) {
+	if l.flags == nil {
+		l.flags = make(map[string]string)
+	}
+	for _, f := range fields() {
+		name := f.name
+		usage := fmt.Sprintf("override %s from config", name)
+		if f.env != "" {
+			usage += fmt.Sprintf(" (env %s)", f.env)
+		}
+		fs.Func(FlagName(name), usage, func(v string) error {
+			l.flags[name] = v
+			return nil
+		})
+	}
+}
+
+// Load builds and validates the configuration, reporting which layer set
+// each field. A missing config file is not an error; the defaults are used.
+func (l *Loader) Load() (*Config, Origins, error) {
+	return l.load(false)
+}
+
+// load is Load; with requireFile set a missing config file is an error
+func (l *Loader) load(requireFile bool) (*Config, Origins, error) {
+	cfg := Default()
+	origins := make(Origins)
+	all := fields()
+	for _, f := range all {
+		origins[f.name] = LayerDefault
+	}
+	v := reflect.ValueOf(cfg).Elem()
+
+	// Config file
+	data, err := os.ReadFile(l.Path)
+	switch {
+	case err == nil:
+		if err := decodeStrict(data, cfg); err != nil {
+			return nil, nil, fmt.Errorf("error parsing %s: %w", l.Path, err)
+		}
+		var present map[string]json.RawMessage
+		if json.Unmarshal(data, &present) == nil {
+			for name := range present {
+				origins[name] = LayerFile
+			}
+		}
+	case os.IsNotExist(err) && !requireFile:
+		log.Printf("Warning: Could not read %s, using defaults", l.Path)
+	default:
+		return nil, nil, fmt.Errorf("error reading %s: %w", l.Path, err)
+	}
+
+	// Legacy secrets.txt fills in a missing HSL key, even if config.json exists
+	if cfg.HSLKey == "" {
+		if data, err := os.ReadFile(SecretsPath); err == nil {
+			cfg.HSLKey = strings.TrimSpace(string(data))
+			origins["hsl_api_key"] = LayerSecrets
+		}
+	}
+
+	// Environment
+	lookup := l.Env
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	for _, f := range all {
+		if f.env == "" {
+			continue
+		}
+		raw, ok := lookup(f.env)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(f.index), raw); err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %w", f.env, err)
+		}
+		origins[f.name] = LayerEnv
+	}
+
+	// Flags
+	for _, f := range all {
+		raw, ok := l.flags[f.name]
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(f.index), raw); err != nil {
+			return nil, nil, fmt.Errorf("invalid -%s: %w", FlagName(f.name), err)
+		}
+		origins[f.name] = LayerFlag
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, origins, nil
+}
+
+// setField assigns a raw env/flag value. Strings are taken verbatim, other
+// types are parsed as JSON, e.g. INFOBOARD_BUS_STOPS='[{"id":"E2185"}]'.
+// Durations may be given unquoted, e.g. -transport-interval=30s.
+func setField(fv reflect.Value, raw string) error {
+	if fv.Kind() == reflect.String {
+		fv.SetString(raw)
+		return nil
+	}
+	data := []byte(raw)
+	if !json.Valid(data) {
+		data, _ = json.Marshal(raw) // Treat bare words like 30s as strings
+	}
+	target := reflect.New(fv.Type())
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return err
+	}
+	fv.Set(target.Elem())
+	return nil
+}
+
+// Masked returns a copy of the config with secret fields replaced, safe to
+// show in debug output
+func (c *Config) Masked() Config {
+	out := *c
+	v := reflect.ValueOf(&out).Elem()
+	for _, f := range fields() {
+		fv := v.Field(f.index)
+		if f.secret && fv.Kind() == reflect.String && fv.String() != "" {
+			fv.SetString(masked)
+		}
+	}
+	return out
+}
+
+// Print writes the effective config, one field per line with the layer that
+// set it. Secrets are masked.
+func Print(w io.Writer, c *Config, origins Origins) error {
+	m := c.Masked()
+	v := reflect.ValueOf(m)
+	all := fields()
+	sort.Slice(all, func(i, j int) bool { return all[i].name < all[j].name })
+	for _, f := range all {
+		var val strings.Builder
+		enc := json.NewEncoder(&val)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v.Field(f.index).Interface()); err != nil {
+			return err
+		}
+		line := fmt.Sprintf("%-22s = %s  [%s]\n", f.name, strings.TrimSpace(val.String()), origins[f.name])
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
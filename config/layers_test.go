@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoaderPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string // config.json; empty means no file
+		secrets string // secrets.txt; empty means no file
+		env     map[string]string
+		flags   []string
+
+		wantKey       string
+		wantKeyOrigin string
+		wantStops     []string
+		wantStopsFrom string
+		wantInterval  time.Duration
+		wantIntFrom   string
+	}{
+		{
+			name:          "defaults only",
+			wantKeyOrigin: LayerDefault,
+			wantStopsFrom: LayerDefault,
+			wantStops:     nil,
+			wantInterval:  Default().TransportInterval.Duration,
+			wantIntFrom:   LayerDefault,
+		},
+		{
+			name:          "secrets.txt fills the key",
+			secrets:       "secret-key\n",
+			wantKey:       "secret-key",
+			wantKeyOrigin: LayerSecrets,
+			wantStops:     nil,
+			wantStopsFrom: LayerDefault,
+			wantInterval:  Default().TransportInterval.Duration,
+			wantIntFrom:   LayerDefault,
+		},
+		{
+			name:          "file beats secrets.txt",
+			file:          `{"hsl_api_key": "file-key", "bus_stops": [{"id": "E2185"}], "transport_interval": "45s"}`,
+			secrets:       "secret-key",
+			wantKey:       "file-key",
+			wantKeyOrigin: LayerFile,
+			wantStops:     []string{"E2185"},
+			wantStopsFrom: LayerFile,
+			wantInterval:  45 * time.Second,
+			wantIntFrom:   LayerFile,
+		},
+		{
+			name:    "env beats file",
+			file:    `{"hsl_api_key": "file-key", "bus_stops": [{"id": "E2185"}]}`,
+			secrets: "secret-key",
+			env: map[string]string{
+				"INFOBOARD_HSL_KEY":            "env-key",
+				"INFOBOARD_BUS_STOPS":          `[{"id": "E1234"}, {"id": "HSL:1234567"}]`,
+				"INFOBOARD_TRANSPORT_INTERVAL": "2m",
+			},
+			wantKey:       "env-key",
+			wantKeyOrigin: LayerEnv,
+			wantStops:     []string{"E1234", "HSL:1234567"},
+			wantStopsFrom: LayerEnv,
+			wantInterval:  2 * time.Minute,
+			wantIntFrom:   LayerEnv,
+		},
+		{
+			name: "flags beat env",
+			file: `{"hsl_api_key": "file-key"}`,
+			env: map[string]string{
+				"INFOBOARD_HSL_KEY":            "env-key",
+				"INFOBOARD_TRANSPORT_INTERVAL": "2m",
+			},
+			flags:         []string{"-hsl-api-key=flag-key", `-bus-stops=[{"id":"E9999"}]`, "-transport-interval=20s"},
+			wantKey:       "flag-key",
+			wantKeyOrigin: LayerFlag,
+			wantStops:     []string{"E9999"},
+			wantStopsFrom: LayerFlag,
+			wantInterval:  20 * time.Second,
+			wantIntFrom:   LayerFlag,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir()) // secrets.txt is read from the working directory
+			if tt.file != "" {
+				writeFile(t, "config.json", tt.file)
+			}
+			if tt.secrets != "" {
+				writeFile(t, SecretsPath, tt.secrets)
+			}
+			l := &Loader{Path: "config.json", Env: func(name string) (string, bool) {
+				v, ok := tt.env[name]
+				return v, ok
+			}}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			l.BindFlags(fs)
+			if err := fs.Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, origins, err := l.Load()
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.HSLKey != tt.wantKey || origins["hsl_api_key"] != tt.wantKeyOrigin {
+				t.Errorf("hsl_api_key = %q [%s], want %q [%s]", cfg.HSLKey, origins["hsl_api_key"], tt.wantKey, tt.wantKeyOrigin)
+			}
+			var stops []string
+			for _, s := range cfg.BusStops {
+				stops = append(stops, s.ID)
+			}
+			if strings.Join(stops, ",") != strings.Join(tt.wantStops, ",") || origins["bus_stops"] != tt.wantStopsFrom {
+				t.Errorf("bus_stops = %v [%s], want %v [%s]", stops, origins["bus_stops"], tt.wantStops, tt.wantStopsFrom)
+			}
+			if cfg.TransportInterval.Duration != tt.wantInterval || origins["transport_interval"] != tt.wantIntFrom {
+				t.Errorf("transport_interval = %s [%s], want %s [%s]",
+					cfg.TransportInterval.Duration, origins["transport_interval"], tt.wantInterval, tt.wantIntFrom)
+			}
+		})
+	}
+}
+
+func TestLoaderInvalidOverrides(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		flags []string
+		want  string
+	}{
+		{name: "bad env list", env: map[string]string{"INFOBOARD_BUS_STOPS": `[{"id":`}, want: "invalid INFOBOARD_BUS_STOPS"},
+		{name: "bad flag duration", flags: []string{"-transport-interval=soon"}, want: "invalid -transport-interval"},
+		{name: "override fails validation", env: map[string]string{"INFOBOARD_TRANSPORT_INTERVAL": "0s"}, want: "transport_interval must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(t.TempDir())
+			l := &Loader{Path: "config.json", Env: func(name string) (string, bool) {
+				v, ok := tt.env[name]
+				return v, ok
+			}}
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			l.BindFlags(fs)
+			if err := fs.Parse(tt.flags); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := l.Load(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.HSLKey = "very-secret-key"
+	origins := Origins{"hsl_api_key": LayerEnv, "port": LayerFlag}
+
+	if m := cfg.Masked(); m.HSLKey != masked || cfg.HSLKey != "very-secret-key" {
+		t.Errorf("Masked() key = %q, original = %q", m.HSLKey, cfg.HSLKey)
+	}
+
+	var out bytes.Buffer
+	if err := Print(&out, cfg, origins); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	if strings.Contains(text, "very-secret-key") {
+		t.Errorf("printed config contains the key:\n%s", text)
+	}
+	for _, want := range []string{
+		`hsl_api_key            = "` + masked + `"  [env]`,
+		`port                   = "` + cfg.Port + `"  [flag]`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("printed config lacks %q:\n%s", want, text)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
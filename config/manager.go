@@ -0,0 +1,129 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Manager holds the active configuration and swaps it atomically when the
+// config file changes. Readers call Get on every use instead of keeping the
+// returned pointer, so they always see the latest valid configuration.
+type Manager struct {
+	loader *Loader
+	cur    atomic.Pointer[Config]
+
+	mu        sync.Mutex // Serializes reloads and guards the fields below
+	origins   Origins
+	listeners []func(prev, next *Config)
+	modTime   time.Time
+	size      int64
+}
+
+// NewManager loads and validates the config using loader. The loader's file
+// is watched; its env and flag layers are reapplied on every reload.
+func NewManager(loader *Loader) (*Manager, error) {
+	cfg, origins, err := loader.Load()
+	if err != nil {
+		return nil, err
+	}
+	m := &Manager{loader: loader, origins: origins}
+	m.cur.Store(cfg)
+	m.modTime, m.size = m.stat()
+	return m, nil
+}
+
+// Static returns a Manager that always holds cfg and never reloads, for
+// tests and tools that build their config in code
+func Static(cfg *Config) *Manager {
+	m := &Manager{}
+	m.cur.Store(cfg)
+	return m
+}
+
+// Get returns the current configuration. The result must not be modified.
+func (m *Manager) Get() *Config {
+	return m.cur.Load()
+}
+
+// Origins returns which layer set each field of the current configuration
+func (m *Manager) Origins() Origins {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.origins
+}
+
+// OnChange registers fn to be called after every successful reload
+func (m *Manager) OnChange(fn func(prev, next *Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+// Reload re-reads the config file. If it is missing or unreadable, or fails
+// to parse or validate, the current configuration is kept and the error
+// returned; a config file deleted or briefly replaced while the app runs
+// must not reset everything to the defaults.
+func (m *Manager) Reload() error {
+	if m.loader == nil {
+		return errors.New("static config cannot be reloaded")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.modTime, m.size = m.stat()
+	cfg, origins, err := m.loader.load(true)
+	if err != nil {
+		return err
+	}
+	m.origins = origins
+	prev := m.cur.Swap(cfg)
+	for _, fn := range m.listeners {
+		fn(prev, cfg)
+	}
+	return nil
+}
+
+// Watch polls the config file every interval and reloads it when its
+// modification time or size changes. It returns when ctx is cancelled.
+func (m *Manager) Watch(ctx context.Context, interval time.Duration) {
+	if m.loader == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		modTime, size := m.stat()
+		m.mu.Lock()
+		changed := !modTime.Equal(m.modTime) || size != m.size
+		m.mu.Unlock()
+		if !changed {
+			continue
+		}
+
+		log.Printf("Config: %s changed, reloading", m.loader.Path)
+		if err := m.Reload(); err != nil {
+			log.Printf("Config: reload failed, keeping previous config: %v", err)
+		} else {
+			log.Println("Config: reloaded")
+		}
+	}
+}
+
+func (m *Manager) stat() (time.Time, int64) {
+	fi, err := os.Stat(m.loader.Path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return fi.ModTime(), fi.Size()
+}
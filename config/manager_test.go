@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManagerReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+
+	write(`{"weather_location": "Espoo"}`)
+	m, err := NewManager(&Loader{Path: path, Env: noEnv})
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	var changes []string
+	m.OnChange(func(prev, next *Config) {
+		changes = append(changes, prev.WeatherLocation+" -> "+next.WeatherLocation)
+	})
+
+	tests := []struct {
+		name    string
+		file    string
+		remove  bool // Delete config.json instead of writing file
+		dir     bool // Replace config.json with a directory
+		wantErr bool
+		want    string // weather_location in effect afterwards
+	}{
+		{name: "valid change", file: `{"weather_location": "Helsinki"}`, want: "Helsinki"},
+		{name: "invalid value keeps config", file: `{"weather_location": "Turku", "port": ""}`, wantErr: true, want: "Helsinki"},
+		{name: "unknown field keeps config", file: `{"weather_locaton": "Turku"}`, wantErr: true, want: "Helsinki"},
+		{name: "broken JSON keeps config", file: `{"weather_location": `, wantErr: true, want: "Helsinki"},
+		{name: "missing file keeps config", remove: true, wantErr: true, want: "Helsinki"},
+		{name: "unreadable file keeps config", dir: true, wantErr: true, want: "Helsinki"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			switch {
+			case tt.remove:
+				os.Remove(path)
+			case tt.dir:
+				os.Remove(path)
+				if err := os.Mkdir(path, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(path)
+			default:
+				write(tt.file)
+			}
+			err := m.Reload()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := m.Get().WeatherLocation; got != tt.want {
+				t.Errorf("weather_location = %q, want %q", got, tt.want)
+			}
+			if got := m.Origins()["weather_location"]; got != LayerFile {
+				t.Errorf("origin = %q, want %q", got, LayerFile)
+			}
+		})
+	}
+
+	if len(changes) != 1 || changes[0] != "Espoo -> Helsinki" {
+		t.Errorf("OnChange calls = %q, want one for the valid change", changes)
+	}
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleRule overrides a fetch interval during a daily time window.
+// Windows are in local time; a window whose end is before its start wraps
+// past midnight (e.g. 23:00-05:30).
+type ScheduleRule struct {
+	From     string   `json:"from"` // "HH:MM"
+	To       string   `json:"to"`   // "HH:MM", exclusive
+	Interval Duration `json:"interval,omitempty"`
+	Pause    bool     `json:"pause,omitempty"` // Do not fetch at all during the window
+}
+
+// Schedule combines a base interval with time-of-day rules. The first rule
+// whose window contains a time wins.
+type Schedule struct {
+	Base  time.Duration
+	Rules []ScheduleRule
+}
+
+// Interval returns the regular interval in effect at now
+func (s Schedule) Interval(now time.Time) time.Duration {
+	if r := s.match(now); r != nil && !r.Pause && r.Interval.Duration > 0 {
+		return r.Interval.Duration
+	}
+	return s.Base
+}
+
+// Adjust returns the run time to use instead of next, given the previous run
+// at now: earlier if a window starts in between (so a faster interval kicks
+// in on time), and later if next falls in a paused window.
+func (s Schedule) Adjust(now, next time.Time) time.Time {
+	for _, r := range s.Rules {
+		start, _, err := r.window(now)
+		if err != nil {
+			continue
+		}
+		// window() gives the occurrence containing or following now
+		if start.After(now) && start.Before(next) {
+			next = start
+		}
+	}
+
+	// Skip over paused windows; bounded in case rules chain into each other
+	for i := 0; i < len(s.Rules); i++ {
+		r := s.match(next)
+		if r == nil || !r.Pause {
+			break
+		}
+		_, end, err := r.window(next)
+		if err != nil {
+			break
+		}
+		next = end
+	}
+	return next
+}
+
+// SeasonalSchedule is a schedule that only runs between two dates of the
+// year, e.g. the city bike season. The first run after the season ends still
+// happens, so the fetcher can report it; later runs wait for the next start.
+type SeasonalSchedule struct {
+	Schedule
+	Start string // "MM-DD", inclusive; a start after the end wraps the new year
+	End   string // "MM-DD", inclusive
+}
+
+// Adjust applies the schedule in season and otherwise moves next to the
+// start of the next season. A run due at once (next not after now, as for
+// the first run after startup) is kept, so a restart out of season still
+// lets the fetcher report it.
+func (s SeasonalSchedule) Adjust(now, next time.Time) time.Time {
+	if inSeason(now, s.Start, s.End) {
+		return s.Schedule.Adjust(now, next)
+	}
+	if !next.After(now) {
+		return next
+	}
+	start, err := time.Parse("01-02", s.Start)
+	if err != nil {
+		return next // Unreachable, inSeason is true for unparsable dates
+	}
+	begin := time.Date(now.Year(), start.Month(), start.Day(), 0, 0, 0, 0, now.Location())
+	if !begin.After(now) {
+		begin = begin.AddDate(1, 0, 0)
+	}
+	return begin
+}
+
+// inSeason reports whether t's date is between the "MM-DD" dates start and
+// end, inclusive. Unparsable dates count as always in season.
+func inSeason(t time.Time, start, end string) bool {
+	from, err1 := time.Parse("01-02", start)
+	to, err2 := time.Parse("01-02", end)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	day := time.Date(0, t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	from = time.Date(0, from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to = time.Date(0, to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	if !to.Before(from) {
+		return !day.Before(from) && !day.After(to)
+	}
+	// Season wraps the new year
+	return !day.Before(from) || !day.After(to)
+}
+
+// match returns the first rule whose window contains t
+func (s Schedule) match(t time.Time) *ScheduleRule {
+	for i := range s.Rules {
+		start, end, err := s.Rules[i].window(t)
+		if err == nil && !t.Before(start) && t.Before(end) {
+			return &s.Rules[i]
+		}
+	}
+	return nil
+}
+
+// window returns the occurrence of the rule's window that contains t, or the
+// next one after t if t is outside it
+func (r ScheduleRule) window(t time.Time) (time.Time, time.Time, error) {
+	from, err := parseClock(r.From)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	to, err := parseClock(r.To)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	wraps := to <= from
+
+	// Yesterday's occurrence may still be running if the window wraps midnight
+	for _, day := range []int{-1, 0, 1} {
+		start := clockOn(t, day, from)
+		end := clockOn(t, day, to)
+		if wraps {
+			end = clockOn(t, day+1, to)
+		}
+		if t.Before(end) {
+			return start, end, nil
+		}
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("no window found") // Unreachable
+}
+
+func (r ScheduleRule) validate() error {
+	if _, err := parseClock(r.From); err != nil {
+		return fmt.Errorf("from: %w", err)
+	}
+	if _, err := parseClock(r.To); err != nil {
+		return fmt.Errorf("to: %w", err)
+	}
+	if r.From == r.To {
+		return fmt.Errorf("from and to must differ")
+	}
+	switch {
+	case r.Pause && r.Interval.Duration != 0:
+		return fmt.Errorf("set either interval or pause, not both")
+	case !r.Pause && r.Interval.Duration <= 0:
+		return fmt.Errorf("interval must be positive")
+	}
+	return nil
+}
+
+// clockOn returns the wall-clock time offset from midnight, days after t's date
+func clockOn(t time.Time, days int, offset time.Duration) time.Time {
+	h := int(offset / time.Hour)
+	m := int(offset % time.Hour / time.Minute)
+	return time.Date(t.Year(), t.Month(), t.Day()+days, h, m, 0, 0, t.Location())
+}
+
+// parseClock parses "HH:MM" into an offset from midnight
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+	s := Schedule{
+		Base: 5 * time.Minute,
+		Rules: []ScheduleRule{
+			{From: "07:00", To: "09:00", Interval: Duration{Duration: time.Minute}}, // Rush hour
+			{From: "23:00", To: "05:30", Pause: true},                               // Night, wraps midnight
+		},
+	}
+
+	t.Run("Interval", func(t *testing.T) {
+		tests := []struct {
+			name string
+			now  time.Time
+			want time.Duration
+		}{
+			{name: "base", now: at(16, 12, 0), want: 5 * time.Minute},
+			{name: "inside window", now: at(16, 7, 0), want: time.Minute},
+			{name: "window end is exclusive", now: at(16, 9, 0), want: 5 * time.Minute},
+			{name: "paused window keeps base", now: at(16, 23, 30), want: 5 * time.Minute},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := s.Interval(tt.now); got != tt.want {
+					t.Errorf("Interval(%s) = %s, want %s", tt.now.Format("15:04"), got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("Adjust", func(t *testing.T) {
+		tests := []struct {
+			name      string
+			now, next time.Time
+			want      time.Time
+		}{
+			{name: "unchanged", now: at(16, 12, 0), next: at(16, 12, 5), want: at(16, 12, 5)},
+			{name: "faster window starts early", now: at(16, 6, 58), next: at(16, 7, 3), want: at(16, 7, 0)},
+			{name: "paused before midnight", now: at(16, 22, 58), next: at(16, 23, 3), want: at(17, 5, 30)},
+			{name: "paused after midnight", now: at(17, 0, 10), next: at(17, 0, 15), want: at(17, 5, 30)},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := s.Adjust(tt.now, tt.next); !got.Equal(tt.want) {
+					t.Errorf("Adjust() = %s, want %s", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+func TestScheduleRuleValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    ScheduleRule
+		wantErr bool
+	}{
+		{name: "interval", rule: ScheduleRule{From: "07:00", To: "09:00", Interval: Duration{Duration: time.Minute}}},
+		{name: "pause", rule: ScheduleRule{From: "23:00", To: "05:30", Pause: true}},
+		{name: "bad clock", rule: ScheduleRule{From: "7am", To: "09:00", Pause: true}, wantErr: true},
+		{name: "empty window", rule: ScheduleRule{From: "07:00", To: "07:00", Pause: true}, wantErr: true},
+		{name: "pause and interval", rule: ScheduleRule{From: "07:00", To: "09:00", Pause: true, Interval: Duration{Duration: time.Minute}}, wantErr: true},
+		{name: "neither", rule: ScheduleRule{From: "07:00", To: "09:00"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInBikeSeason(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2026, month, d, 12, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name       string
+		start, end string
+		t          time.Time
+		want       bool
+	}{
+		{name: "in season", start: "04-01", end: "10-31", t: day(7, 15), want: true},
+		{name: "first day", start: "04-01", end: "10-31", t: day(4, 1), want: true},
+		{name: "last day", start: "04-01", end: "10-31", t: day(10, 31), want: true},
+		{name: "before season", start: "04-01", end: "10-31", t: day(3, 31)},
+		{name: "after season", start: "04-01", end: "10-31", t: day(11, 1)},
+		{name: "wraps, december", start: "11-15", end: "02-28", t: day(12, 24), want: true},
+		{name: "wraps, january", start: "11-15", end: "02-28", t: day(1, 10), want: true},
+		{name: "wraps, summer", start: "11-15", end: "02-28", t: day(7, 1)},
+		{name: "unparsable", start: "spring", end: "10-31", t: day(1, 1), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Config{BikeSeasonStart: tt.start, BikeSeasonEnd: tt.end}
+			if got := c.InBikeSeason(tt.t); got != tt.want {
+				t.Errorf("InBikeSeason(%s) = %v, want %v", tt.t.Format("01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeasonalScheduleAdjust(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	s := SeasonalSchedule{Schedule: Schedule{Base: 10 * time.Minute}, Start: "04-01", End: "10-31"}
+	tests := []struct {
+		name      string
+		now, next time.Time
+		want      time.Time
+	}{
+		{name: "in season", now: at(2026, 7, 1, 12, 0), next: at(2026, 7, 1, 12, 10), want: at(2026, 7, 1, 12, 10)},
+		{name: "first run after the season still happens", now: at(2026, 10, 31, 23, 55), next: at(2026, 11, 1, 0, 5), want: at(2026, 11, 1, 0, 5)},
+		{name: "after the season waits for spring", now: at(2026, 11, 1, 0, 5), next: at(2026, 11, 1, 0, 15), want: at(2027, 4, 1, 0, 0)},
+		{name: "before the season", now: at(2026, 2, 10, 8, 0), next: at(2026, 2, 10, 8, 10), want: at(2026, 4, 1, 0, 0)},
+		{name: "first run after startup out of season", now: at(2026, 2, 10, 8, 0), next: at(2026, 2, 10, 8, 0), want: at(2026, 2, 10, 8, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Adjust(tt.now, tt.next); !got.Equal(tt.want) {
+				t.Errorf("Adjust() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
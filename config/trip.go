@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Itinerary counts per trip
+const (
+	DefaultItineraries = 3
+	MaxItineraries     = 5
+)
+
+// Trip is a saved origin-destination pair for the journey planner
+type Trip struct {
+	Name string `json:"name"`
+	From Place  `json:"from"`
+	To   Place  `json:"to"`
+
+	// Optional "HH:MM" local time to arrive by, e.g. "09:00" for work.
+	// Without it, itineraries departing now are planned.
+	ArriveBy string `json:"arrive_by,omitempty"`
+
+	Itineraries int `json:"itineraries,omitempty"` // How many to keep, default 3
+}
+
+// Place is a location given by coordinates
+type Place struct {
+	Name string  `json:"name,omitempty"`
+	Lat  float64 `json:"lat"`
+	Lon  float64 `json:"lon"`
+}
+
+// ItineraryCount returns how many itineraries to plan
+func (t Trip) ItineraryCount() int {
+	if t.Itineraries > 0 {
+		return t.Itineraries
+	}
+	return DefaultItineraries
+}
+
+// ArrivalAfter returns the next arrive-by time after now: today if it has
+// not passed yet, else tomorrow. ok is false for depart-now trips.
+func (t Trip) ArrivalAfter(now time.Time) (arrival time.Time, ok bool) {
+	if t.ArriveBy == "" {
+		return time.Time{}, false
+	}
+	offset, err := parseClock(t.ArriveBy)
+	if err != nil {
+		return time.Time{}, false
+	}
+	arrival = clockOn(now, 0, offset)
+	if !arrival.After(now) {
+		arrival = clockOn(now, 1, offset)
+	}
+	return arrival, true
+}
+
+func (t Trip) validate() error {
+	var errs []error
+	if t.Name == "" {
+		errs = append(errs, errors.New("name must not be empty"))
+	}
+	if err := t.From.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("from: %w", err))
+	}
+	if err := t.To.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("to: %w", err))
+	}
+	if t.ArriveBy != "" {
+		if _, err := parseClock(t.ArriveBy); err != nil {
+			errs = append(errs, fmt.Errorf("arrive_by: %w", err))
+		}
+	}
+	if t.Itineraries < 0 || t.Itineraries > MaxItineraries {
+		errs = append(errs, fmt.Errorf("itineraries must be between 1 and %d", MaxItineraries))
+	}
+	return errors.Join(errs...)
+}
+
+func (p Place) validate() error {
+	if p.Lat < -90 || p.Lat > 90 || p.Lon < -180 || p.Lon > 180 || (p.Lat == 0 && p.Lon == 0) {
+		return fmt.Errorf("invalid coordinates %v,%v", p.Lat, p.Lon)
+	}
+	return nil
+}
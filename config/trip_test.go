@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTripArrivalAfter(t *testing.T) {
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name     string
+		arriveBy string
+		now      time.Time
+		want     time.Time
+		wantOK   bool
+	}{
+		{name: "depart now", now: at(10, 16, 8, 0)},
+		{name: "later today", arriveBy: "09:00", now: at(10, 16, 8, 0), want: at(10, 16, 9, 0), wantOK: true},
+		{name: "passed today", arriveBy: "09:00", now: at(10, 16, 9, 30), want: at(10, 17, 9, 0), wantOK: true},
+		{name: "exactly now is tomorrow", arriveBy: "09:00", now: at(10, 16, 9, 0), want: at(10, 17, 9, 0), wantOK: true},
+		{name: "just after midnight from late evening", arriveBy: "00:15", now: at(10, 16, 23, 50), want: at(10, 17, 0, 15), wantOK: true},
+		{name: "late evening from just after midnight", arriveBy: "23:55", now: at(10, 17, 0, 10), want: at(10, 17, 23, 55), wantOK: true},
+		{name: "midnight", arriveBy: "00:00", now: at(10, 16, 23, 59), want: at(10, 17, 0, 0), wantOK: true},
+		{name: "month end", arriveBy: "07:30", now: at(10, 31, 22, 0), want: at(11, 1, 7, 30), wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Trip{ArriveBy: tt.arriveBy}.ArrivalAfter(tt.now)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Errorf("ArrivalAfter(%s) = %s, %v; want %s, %v", tt.now.Format("Jan 2 15:04"), got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
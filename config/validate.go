@@ -0,0 +1,142 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+var (
+	// Full GTFS stop id, e.g. HSL:1234567
+	gtfsStopID = regexp.MustCompile(`^HSL:\d+$`)
+	// Short code printed on the stop sign, e.g. E2185, H1234 or 1234
+	stopShortCode = regexp.MustCompile(`^[A-Za-z]{0,2}\d{3,5}$`)
+)
+
+// Validate checks the configuration and returns all problems found, joined
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Port == "" {
+		errs = append(errs, errors.New("port must not be empty"))
+	}
+
+	errs = append(errs,
+		checkInterval("weather_interval", c.WeatherInterval.Duration),
+		checkInterval("transport_interval", c.TransportInterval.Duration),
+		checkInterval("electricity_interval", c.ElectricityInterval.Duration),
+		checkInterval("planner_interval", c.PlannerInterval.Duration),
+		checkInterval("bike_interval", c.BikeInterval.Duration),
+		checkInterval("warnings_interval", c.WarningsInterval.Duration),
+		checkMonthDay("bike_season_start", c.BikeSeasonStart),
+		checkMonthDay("bike_season_end", c.BikeSeasonEnd),
+		checkInterval("stop_cache_ttl", c.StopCacheTTL.Duration),
+		checkURL("hsl_api_url", c.HSLAPIUrl),
+		checkURL("geocoding_api_url", c.GeocodingAPIUrl),
+		checkURL("fmi_api_url", c.FMIAPIUrl),
+		checkURL("spot_api_url", c.SpotAPIUrl),
+		checkURL("warnings_api_url", c.WarningsAPIUrl),
+		checkInterval("http_timeout", c.HTTPTimeout.Duration),
+	)
+	if c.HTTPRetries < 0 || c.HTTPRetries > MaxHTTPRetries {
+		errs = append(errs, fmt.Errorf("http_retries must be between 0 and %d", MaxHTTPRetries))
+	}
+
+	errs = append(errs, checkSchedule("weather_schedule", c.WeatherSchedule)...)
+	errs = append(errs, checkSchedule("transport_schedule", c.TransportSchedule)...)
+	errs = append(errs, checkSchedule("electricity_schedule", c.ElectricitySchedule)...)
+
+	if len(c.WeatherLocations) == 0 && c.WeatherLocation == "" {
+		errs = append(errs, errors.New("weather_location must not be empty"))
+	}
+	names := make(map[string]bool)
+	for i, p := range c.WeatherLocations {
+		if err := p.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("weather_locations[%d]: %w", i, err))
+		}
+		if names[p.Name] {
+			errs = append(errs, fmt.Errorf("weather_locations[%d]: duplicate name %q", i, p.Name))
+		}
+		names[p.Name] = true
+	}
+
+	seen := make(map[string]bool)
+	for i, stop := range c.BusStops {
+		switch {
+		case stop.ID == "":
+			errs = append(errs, fmt.Errorf("bus_stops[%d]: id must not be empty", i))
+		case !gtfsStopID.MatchString(stop.ID) && !stopShortCode.MatchString(stop.ID):
+			errs = append(errs, fmt.Errorf("bus_stops[%d]: invalid stop id %q (expected e.g. E2185 or HSL:1234567)", i, stop.ID))
+		case seen[stop.ID]:
+			errs = append(errs, fmt.Errorf("bus_stops[%d]: duplicate stop id %q", i, stop.ID))
+		}
+		seen[stop.ID] = true
+
+		if stop.Departures < 0 || stop.Departures > MaxDepartures {
+			errs = append(errs, fmt.Errorf("bus_stops[%d]: departures must be between 1 and %d", i, MaxDepartures))
+		}
+		if stop.WalkingTime.Duration < 0 || stop.WalkingTime.Duration > time.Hour {
+			errs = append(errs, fmt.Errorf("bus_stops[%d]: walking_time must be between 0 and 1h", i))
+		}
+		if len(stop.Routes) > 0 && len(stop.ExcludeRoutes) > 0 {
+			errs = append(errs, fmt.Errorf("bus_stops[%d]: set either routes or exclude_routes, not both", i))
+		}
+	}
+
+	for i, b := range c.BikeStations {
+		if b.ID == "" {
+			errs = append(errs, fmt.Errorf("bike_stations[%d]: id must not be empty", i))
+		}
+	}
+
+	for i, t := range c.Trips {
+		if err := t.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("trips[%d]: %w", i, err))
+		}
+	}
+
+	for i, a := range c.Appliances {
+		if err := a.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("appliances[%d]: %w", i, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func checkInterval(name string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("%s must be positive, got %s", name, d)
+	}
+	return nil
+}
+
+func checkSchedule(name string, rules []ScheduleRule) []error {
+	var errs []error
+	for i, r := range rules {
+		if err := r.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %w", name, i, err))
+		}
+	}
+	return errs
+}
+
+func checkMonthDay(name, s string) error {
+	if _, err := time.Parse("01-02", s); err != nil {
+		return fmt.Errorf("%s: invalid date %q, expected MM-DD", name, s)
+	}
+	return nil
+}
+
+func checkURL(name, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("%s: must be an absolute http(s) URL, got %q", name, raw)
+	}
+	return nil
+}
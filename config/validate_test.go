@@ -0,0 +1,91 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mutate  func(c *Config)
+		wantErr []string // Substrings of the joined error; none means valid
+	}{
+		{name: "defaults", mutate: func(c *Config) {}},
+		{
+			name: "valid stops and appliances",
+			mutate: func(c *Config) {
+				c.BusStops = []BusStop{{ID: "E2185", Routes: []string{"110"}}, {ID: "HSL:1234567", WalkingTime: Duration{Duration: 5 * time.Minute}}}
+				c.Appliances = []Appliance{{Name: "Dishwasher", Duration: Duration{Duration: 3 * time.Hour}}}
+			},
+		},
+		{name: "empty port", mutate: func(c *Config) { c.Port = "" }, wantErr: []string{"port must not be empty"}},
+		{name: "zero interval", mutate: func(c *Config) { c.WeatherInterval = Duration{} }, wantErr: []string{"weather_interval must be positive"}},
+		{name: "relative URL", mutate: func(c *Config) { c.FMIAPIUrl = "/wfs" }, wantErr: []string{"fmi_api_url: must be an absolute http(s) URL"}},
+		{name: "bad season date", mutate: func(c *Config) { c.BikeSeasonStart = "4-1" }, wantErr: []string{`bike_season_start: invalid date "4-1"`}},
+		{name: "too many retries", mutate: func(c *Config) { c.HTTPRetries = MaxHTTPRetries + 1 }, wantErr: []string{"http_retries must be between"}},
+		{
+			name:    "bad stop id",
+			mutate:  func(c *Config) { c.BusStops = []BusStop{{ID: "Kamppi"}} },
+			wantErr: []string{`bus_stops[0]: invalid stop id "Kamppi"`},
+		},
+		{
+			name:    "duplicate stop",
+			mutate:  func(c *Config) { c.BusStops = []BusStop{{ID: "E2185"}, {ID: "E2185"}} },
+			wantErr: []string{`bus_stops[1]: duplicate stop id "E2185"`},
+		},
+		{
+			name: "routes and exclude_routes",
+			mutate: func(c *Config) {
+				c.BusStops = []BusStop{{ID: "E2185", Routes: []string{"110"}, ExcludeRoutes: []string{"114"}}}
+			},
+			wantErr: []string{"bus_stops[0]: set either routes or exclude_routes"},
+		},
+		{
+			name: "appliance shorter than a price slot",
+			mutate: func(c *Config) {
+				c.Appliances = []Appliance{{Name: "Kettle", Duration: Duration{Duration: 5 * time.Minute}}}
+			},
+			wantErr: []string{"appliances[0]: duration: must be between 15m0s and 24h0m0s"},
+		},
+		{
+			name: "appliance off the price slots",
+			mutate: func(c *Config) {
+				c.Appliances = []Appliance{{Name: "Sauna", Duration: Duration{Duration: 50 * time.Minute}}}
+			},
+			wantErr: []string{"appliances[0]: duration: must be a multiple of 15m0s"},
+		},
+		{
+			name: "all problems reported",
+			mutate: func(c *Config) {
+				c.Port = ""
+				c.TransportInterval = Duration{Duration: -time.Second}
+				c.BusStops = []BusStop{{}}
+			},
+			wantErr: []string{"port must not be empty", "transport_interval must be positive", "bus_stops[0]: id must not be empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.mutate(c)
+			err := c.Validate()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validate() succeeded")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WeatherPlace is a named forecast location. Set exactly one of Place,
+// LatLon or FMISID.
+type WeatherPlace struct {
+	Name    string `json:"name"`
+	Place   string `json:"place,omitempty"`   // FMI place name, e.g. "Espoo"
+	LatLon  string `json:"latlon,omitempty"`  // "lat,lon", e.g. "61.05,28.19"
+	FMISID  string `json:"fmisid,omitempty"`  // Weather station id, e.g. "100971"
+	Station string `json:"station,omitempty"` // Observation station (fmisid or place), default the nearest one
+}
+
+// WeatherPlaces returns the configured weather locations. Without
+// weather_locations, weather_location and weather_station make up a single one.
+func (c *Config) WeatherPlaces() []WeatherPlace {
+	if len(c.WeatherLocations) > 0 {
+		return c.WeatherLocations
+	}
+	return []WeatherPlace{{Name: c.WeatherLocation, Place: c.WeatherLocation, Station: c.WeatherStation}}
+}
+
+// Coordinates parses LatLon. ok is false if it is not set or invalid.
+func (p WeatherPlace) Coordinates() (lat, lon float64, ok bool) {
+	latStr, lonStr, found := strings.Cut(p.LatLon, ",")
+	if !found {
+		return 0, 0, false
+	}
+	lat, err1 := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+	lon, err2 := strconv.ParseFloat(strings.TrimSpace(lonStr), 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+func (p WeatherPlace) validate() error {
+	var errs []error
+	if p.Name == "" {
+		errs = append(errs, errors.New("name must not be empty"))
+	}
+	set := 0
+	for _, v := range []string{p.Place, p.LatLon, p.FMISID} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		errs = append(errs, errors.New("set exactly one of place, latlon or fmisid"))
+	}
+	if p.LatLon != "" {
+		lat, lon, ok := p.Coordinates()
+		if !ok {
+			errs = append(errs, fmt.Errorf("latlon: invalid coordinates %q, expected e.g. \"60.17,24.94\"", p.LatLon))
+		} else if err := (Place{Lat: lat, Lon: lon}).validate(); err != nil {
+			errs = append(errs, fmt.Errorf("latlon: %w", err))
+		}
+	}
+	if p.FMISID != "" {
+		if _, err := strconv.Atoi(p.FMISID); err != nil {
+			errs = append(errs, fmt.Errorf("fmisid: must be numeric, got %q", p.FMISID))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"rasp_info/store"
+	"time"
+)
+
+// heartbeatInterval keeps idle SSE connections alive through proxies and
+// lets the browser notice dead connections
+const heartbeatInterval = 15 * time.Second
+
+// serveEvents streams store section updates as Server-Sent Events. Each event
+// is named after its section and carries the section JSON as data. The
+// current state is sent on connect, so reconnecting clients resync fully.
+// Streams end when the client disconnects or appCtx is cancelled.
+func serveEvents(appCtx context.Context, st *store.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		events, cancel := st.Subscribe()
+		defer cancel()
+
+		// Ask the browser to reconnect quickly if the stream drops
+		fmt.Fprint(w, "retry: 5000\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-appCtx.Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				payload, err := json.Marshal(ev.Data)
+				if err != nil {
+					log.Printf("Error encoding %s event: %v", ev.Section, err)
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Section, payload)
+				flusher.Flush()
+			}
+		}
+	}
+}
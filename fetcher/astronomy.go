@@ -0,0 +1,80 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"rasp_info/astro"
+	"rasp_info/config"
+	"rasp_info/store"
+	"time"
+)
+
+// AstronomyFetcher computes today's sunrise, sunset, day length and moon
+// phase for the first weather location. Nothing is fetched; it runs on the
+// scheduler like the other sources so the result is refreshed daily.
+type AstronomyFetcher struct {
+	Config *config.Manager
+	Store  *store.Store
+}
+
+func (f *AstronomyFetcher) Fetch(ctx context.Context) error {
+	cfg := f.Config.Get()
+	place := cfg.WeatherPlaces()[0]
+	weather := f.Store.Get().Weather
+	lat, lon, ok := placeCoordinates(place, weather.Locations)
+	if !ok {
+		if weather.FetchedAt.IsZero() {
+			// Place names and stations are resolved by the first weather fetch
+			return fmt.Errorf("%w: no coordinates for %s", ErrNotReady, place.Name)
+		}
+		return fmt.Errorf("no coordinates for %s", place.Name)
+	}
+
+	data := astronomyData(place.Name, lat, lon, time.Now())
+	log.Printf("Astronomy: %s sunrise %s, sunset %s, day length %s, moon %s",
+		place.Name, data.Sunrise.Format("15:04"), data.Sunset.Format("15:04"),
+		time.Duration(data.DayLength)*time.Second, data.MoonPhaseName)
+	f.Store.UpdateAstronomy(data)
+	return nil
+}
+
+// astronomyData computes the sun and moon for the local day containing now
+func astronomyData(name string, lat, lon float64, now time.Time) store.AstronomyData {
+	today := astro.Sun(now, lat, lon)
+	yesterday := astro.Sun(now.AddDate(0, 0, -1), lat, lon)
+	moon := astro.MoonAt(now)
+	return store.AstronomyData{
+		SectionMeta:      store.SectionMeta{Source: "local"},
+		Location:         name,
+		Lat:              lat,
+		Lon:              lon,
+		Date:             now.Format("2006-01-02"),
+		Sunrise:          today.Sunrise,
+		Sunset:           today.Sunset,
+		Dawn:             today.Dawn,
+		Dusk:             today.Dusk,
+		DayLength:        int(today.DayLength.Seconds()),
+		DayLengthChange:  int((today.DayLength - yesterday.DayLength).Seconds()),
+		PolarDay:         today.PolarDay,
+		PolarNight:       today.PolarNight,
+		MoonPhase:        math.Round(moon.Phase*1000) / 1000,
+		MoonIllumination: math.Round(moon.Illumination * 100),
+		MoonPhaseName:    moon.Name,
+	}
+}
+
+// placeCoordinates returns a weather location's configured coordinates, or
+// those FMI resolved for it in the last weather fetch
+func placeCoordinates(place config.WeatherPlace, fetched []store.LocationWeather) (lat, lon float64, ok bool) {
+	if lat, lon, ok := place.Coordinates(); ok {
+		return lat, lon, true
+	}
+	for _, l := range fetched {
+		if l.Name == place.Name && (l.Lat != 0 || l.Lon != 0) {
+			return l.Lat, l.Lon, true
+		}
+	}
+	return 0, 0, false
+}
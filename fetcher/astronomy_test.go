@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"rasp_info/config"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+func TestAstronomyData(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Skip("no time zone data:", err)
+	}
+	clock := func(hhmm string, day time.Time) time.Time {
+		c, _ := time.ParseInLocation("15:04", hhmm, helsinki)
+		y, m, d := day.Date()
+		return time.Date(y, m, d, c.Hour(), c.Minute(), 0, 0, helsinki)
+	}
+	near := func(got, want time.Time) bool {
+		return absDiff(got, want) <= 2*time.Minute
+	}
+
+	tests := []struct {
+		name                   string
+		lat, lon               float64
+		day                    time.Time
+		sunrise, sunset        string // Local "HH:MM", empty if none
+		dayLength              time.Duration
+		wantPolarDay, wantDark bool
+	}{
+		{
+			name: "Helsinki midsummer", lat: 60.1699, lon: 24.9384,
+			day:     time.Date(2026, 6, 21, 12, 0, 0, 0, helsinki),
+			sunrise: "03:54", sunset: "22:50", dayLength: 18*time.Hour + 56*time.Minute,
+		},
+		{
+			name: "Helsinki autumn", lat: 60.1699, lon: 24.9384,
+			day:     time.Date(2026, 10, 16, 12, 0, 0, 0, helsinki),
+			sunrise: "08:02", sunset: "18:08", dayLength: 10*time.Hour + 6*time.Minute,
+		},
+		{
+			name: "Utsjoki polar night", lat: 69.9, lon: 27.0,
+			day:      time.Date(2026, 12, 21, 12, 0, 0, 0, helsinki),
+			wantDark: true,
+		},
+		{
+			name: "Utsjoki midnight sun", lat: 69.9, lon: 27.0,
+			day:          time.Date(2026, 6, 21, 12, 0, 0, 0, helsinki),
+			dayLength:    24 * time.Hour,
+			wantPolarDay: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := astronomyData("Home", tt.lat, tt.lon, tt.day)
+			if tt.sunrise != "" && !near(a.Sunrise, clock(tt.sunrise, tt.day)) {
+				t.Errorf("sunrise = %v, want %s", a.Sunrise.In(helsinki), tt.sunrise)
+			}
+			if tt.sunset != "" && !near(a.Sunset, clock(tt.sunset, tt.day)) {
+				t.Errorf("sunset = %v, want %s", a.Sunset.In(helsinki), tt.sunset)
+			}
+			if tt.sunrise == "" && (!a.Sunrise.IsZero() || !a.Sunset.IsZero()) {
+				t.Errorf("sunrise %v, sunset %v, want none", a.Sunrise, a.Sunset)
+			}
+			if d := time.Duration(a.DayLength) * time.Second; (d - tt.dayLength).Abs() > 3*time.Minute {
+				t.Errorf("day length = %s, want %s", d, tt.dayLength)
+			}
+			if a.PolarDay != tt.wantPolarDay || a.PolarNight != tt.wantDark {
+				t.Errorf("polar day %v, polar night %v", a.PolarDay, a.PolarNight)
+			}
+			if a.Date != tt.day.Format("2006-01-02") {
+				t.Errorf("date = %s", a.Date)
+			}
+		})
+	}
+
+	// Days shorten by about 5 minutes a day in Helsinki in October
+	a := astronomyData("Home", 60.1699, 24.9384, time.Date(2026, 10, 16, 12, 0, 0, 0, helsinki))
+	if a.DayLengthChange > -4*60 || a.DayLengthChange < -6*60 {
+		t.Errorf("day length change = %ds, want about -5 min", a.DayLengthChange)
+	}
+
+	// Full moon on 2026-10-26 at 04:12 UTC
+	a = astronomyData("Home", 60.1699, 24.9384, time.Date(2026, 10, 26, 4, 12, 0, 0, time.UTC))
+	if a.MoonPhaseName != "full" || a.MoonIllumination < 99 {
+		t.Errorf("moon = %s, %v%%, want full", a.MoonPhaseName, a.MoonIllumination)
+	}
+}
+
+func TestAstronomyFetcher(t *testing.T) {
+	st := store.New()
+	cfg := testConfig(func(c *config.Config) {
+		c.WeatherLocations = []config.WeatherPlace{{Name: "Home", Place: "Espoo"}}
+	})
+	f := &AstronomyFetcher{Config: cfg, Store: st}
+
+	// The place has not been resolved by a weather fetch yet
+	if err := f.Fetch(context.Background()); !errors.Is(err, ErrNotReady) {
+		t.Fatalf("Fetch() before the first weather fetch: error = %v, want ErrNotReady", err)
+	}
+
+	// The weather fetch could not resolve it
+	st.UpdateWeather(store.WeatherData{})
+	if err := f.Fetch(context.Background()); err == nil || errors.Is(err, ErrNotReady) {
+		t.Fatalf("Fetch() without resolved coordinates: error = %v, want a failure", err)
+	}
+
+	st.UpdateWeather(store.WeatherData{Locations: []store.LocationWeather{{Name: "Home", Lat: 60.2, Lon: 24.66}}})
+	if err := f.Fetch(context.Background()); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	a := st.Get().Astronomy
+	if a.Location != "Home" || a.Lat != 60.2 || a.Lon != 24.66 || a.FetchedAt.IsZero() || a.MoonPhaseName == "" {
+		t.Errorf("astronomy = %+v", a)
+	}
+}
@@ -0,0 +1,109 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"rasp_info/config"
+	"rasp_info/store"
+	"time"
+)
+
+// bikeStationQueryFields is the body of one aliased station field; the
+// placeholder is the variable reference for the station id
+const bikeStationQueryFields = `
+  vehicleRentalStation(id: %s) {
+    stationId
+    name
+    operative
+    realtime
+    availableVehicles {
+      total
+    }
+    availableSpaces {
+      total
+    }
+  }
+`
+
+// CityBikeFetcher reports bike and dock availability at configured city bike
+// stations, using the same Digitransit endpoint and key as HSLFetcher.
+// Outside the configured season no requests are made; schedule it with
+// config.BikeTiming so it does not run then either.
+type CityBikeFetcher struct {
+	Config *config.Manager
+	Store  *store.Store
+	HTTP   *HTTPClient // Shared HTTP layer; nil uses defaults
+}
+
+type BikeStationResponse struct {
+	StationID         string `json:"stationId"`
+	Name              string `json:"name"`
+	Operative         bool   `json:"operative"`
+	Realtime          bool   `json:"realtime"`
+	AvailableVehicles struct {
+		Total int `json:"total"`
+	} `json:"availableVehicles"`
+	AvailableSpaces struct {
+		Total int `json:"total"`
+	} `json:"availableSpaces"`
+}
+
+func (f *CityBikeFetcher) Fetch(ctx context.Context) error {
+	cfg := f.Config.Get()
+	now := time.Now()
+	meta := store.SectionMeta{Source: "digitransit", FetchedAt: now}
+
+	if len(cfg.BikeStations) == 0 {
+		f.Store.UpdateBikes(store.BikesData{SectionMeta: meta})
+		return nil
+	}
+	if !cfg.InBikeSeason(now) {
+		// Stations are closed for the winter; nothing to ask the API. BikeTiming
+		// pauses after this run, so the stations are cleared once per season.
+		f.Store.UpdateBikes(store.BikesData{SectionMeta: meta, InSeason: false})
+		return nil
+	}
+
+	q := NewQueryBuilder()
+	for i, station := range cfg.BikeStations {
+		q.Field(fmt.Sprintf("station%d", i), fmt.Sprintf(bikeStationQueryFields, q.Var("String!", station.ID)))
+	}
+	query, vars := q.Build()
+
+	var result map[string]*BikeStationResponse
+	if err := newDigitransitClient(cfg, f.HTTP).Do(ctx, query, vars, &result); err != nil {
+		if !IsPartial(err) {
+			return fmt.Errorf("failed to fetch city bike data: %w", err)
+		}
+		log.Printf("CityBike: Partial response: %v", err)
+	}
+
+	var stations []store.BikeStationStatus
+	for i, station := range cfg.BikeStations {
+		s := result[fmt.Sprintf("station%d", i)]
+		if s == nil {
+			log.Printf("CityBike: No data for station %s", station.ID)
+			continue
+		}
+		name := station.Name
+		if name == "" {
+			name = s.Name
+		}
+		stations = append(stations, store.BikeStationStatus{
+			ID:              station.ID,
+			Name:            name,
+			BikesAvailable:  s.AvailableVehicles.Total,
+			SpacesAvailable: s.AvailableSpaces.Total,
+			Operative:       s.Operative,
+			Realtime:        s.Realtime,
+		})
+	}
+
+	f.Store.UpdateBikes(store.BikesData{
+		SectionMeta: meta,
+		InSeason:    true,
+		Stations:    stations,
+	})
+	return nil
+}
@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"rasp_info/config"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+func TestCityBikeFetcher(t *testing.T) {
+	now := time.Now()
+	stations := []config.BikeStation{{ID: "smoove:001", Name: "Kamppi"}, {ID: "smoove:002"}}
+	body := []byte(`{"data":{
+		"station0":{"stationId":"001","name":"Kamppi (M)","operative":true,"realtime":true,"availableVehicles":{"total":4},"availableSpaces":{"total":11}},
+		"station1":{"stationId":"002","name":"Narinkka","operative":false,"realtime":true,"availableVehicles":{"total":0},"availableSpaces":{"total":0}}}}`)
+
+	tests := []struct {
+		name         string
+		stations     []config.BikeStation
+		start, end   string
+		wantRequest  bool
+		wantInSeason bool
+		wantStations []store.BikeStationStatus
+	}{
+		{
+			name:         "in season",
+			stations:     stations,
+			start:        "01-01",
+			end:          "12-31",
+			wantRequest:  true,
+			wantInSeason: true,
+			wantStations: []store.BikeStationStatus{
+				{ID: "smoove:001", Name: "Kamppi", BikesAvailable: 4, SpacesAvailable: 11, Operative: true, Realtime: true},
+				{ID: "smoove:002", Name: "Narinkka", Realtime: true},
+			},
+		},
+		{
+			name:     "out of season",
+			stations: stations,
+			start:    now.AddDate(0, 0, 2).Format("01-02"),
+			end:      now.AddDate(0, 0, 3).Format("01-02"),
+		},
+		{name: "no stations", start: "01-01", end: "12-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, http.StatusOK, body)
+			st := store.New()
+			cfg := testConfig(func(c *config.Config) {
+				c.HSLAPIUrl = srv.URL
+				c.BikeStations = tt.stations
+				c.BikeSeasonStart, c.BikeSeasonEnd = tt.start, tt.end
+			})
+			f := &CityBikeFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+			if err := f.Fetch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if req, _ := srv.last(); (req != nil) != tt.wantRequest {
+				t.Errorf("request made = %v, want %v", req != nil, tt.wantRequest)
+			}
+			bikes := st.Get().Bikes
+			if bikes.InSeason != tt.wantInSeason || bikes.FetchedAt.IsZero() {
+				t.Errorf("in season %v (fetched %s), want %v", bikes.InSeason, bikes.FetchedAt, tt.wantInSeason)
+			}
+			if len(bikes.Stations) != len(tt.wantStations) {
+				t.Fatalf("stations = %+v, want %+v", bikes.Stations, tt.wantStations)
+			}
+			for i, want := range tt.wantStations {
+				if bikes.Stations[i] != want {
+					t.Errorf("station %d = %+v, want %+v", i, bikes.Stations[i], want)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,7 @@
 package fetcher
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -11,8 +11,9 @@ import (
 )
 
 type ElectricityFetcher struct {
-	Config *config.Config
+	Config *config.Manager
 	Store  *store.Store
+	HTTP   *HTTPClient // Shared HTTP layer; nil uses defaults
 }
 
 type SpotPrice struct {
@@ -21,35 +22,30 @@ type SpotPrice struct {
 	DateTime     string  `json:"DateTime"`
 }
 
-func (f *ElectricityFetcher) Fetch() error {
-	resp, err := http.Get(f.Config.SpotAPIUrl)
+func (f *ElectricityFetcher) Fetch(ctx context.Context) error {
+	cfg := f.Config.Get()
+	resp, err := f.HTTP.Get(ctx, cfg.SpotAPIUrl, nil)
 	if err != nil {
 		return fmt.Errorf("failed to fetch electricity prices: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("electricity api returned status: %d", resp.StatusCode)
 	}
 
-	// Read body for logging
-	var bodyBytes bytes.Buffer
-	_, err = bodyBytes.ReadFrom(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read electricity body: %w", err)
-	}
 	// Log the raw response
-	// log.Printf("Electricity Response: %s", bodyBytes.String())
+	// log.Printf("Electricity Response: %s", string(resp.Body))
 
+	// Prices are re-read even when unchanged (304), as the current slot moves
 	var prices []SpotPrice
-	if err := json.Unmarshal(bodyBytes.Bytes(), &prices); err != nil {
+	if err := json.Unmarshal(resp.Body, &prices); err != nil {
 		return fmt.Errorf("failed to decode electricity json: %w", err)
 	}
 
 	now := time.Now()
 	windowEnd := now.Add(24 * time.Hour)
 	var currentPrice float64
-	var priceList []store.PriceInfo
+	var all, priceList []store.PriceInfo
 	currentSet := false
 
 	for _, p := range prices {
@@ -57,39 +53,37 @@ func (f *ElectricityFetcher) Fetch() error {
 		if err != nil {
 			continue
 		}
+		slotEnd := t.Add(config.PriceSlot)
+		priceValue := p.PriceWithTax * 100
+		slot := store.PriceInfo{
+			Price:     priceValue,
+			StartTime: t,
+			EndTime:   slotEnd,
+		}
+		all = append(all, slot) // Whole days, for statistics
 
-		slotEnd := t.Add(15 * time.Minute)
-		if slotEnd.Before(now) {
+		if slotEnd.Before(now) || t.After(windowEnd) || len(priceList) >= 96 {
 			continue
 		}
-		if t.After(windowEnd) {
-			break
-		}
 
-		priceValue := p.PriceWithTax * 100
 		if !currentSet && now.After(t) && now.Before(slotEnd) {
 			currentPrice = priceValue
 			currentSet = true
 		}
 
-		priceList = append(priceList, store.PriceInfo{
-			Price:     priceValue,
-			StartTime: t,
-			EndTime:   slotEnd,
-		})
-
-		if len(priceList) >= 96 {
-			break
-		}
+		priceList = append(priceList, slot)
 	}
 
 	if !currentSet && len(priceList) > 0 {
 		currentPrice = priceList[0].Price
 	}
+	withPercentiles(priceList, all)
 
 	f.Store.UpdateElectricity(store.ElectricityData{
+		SectionMeta:  store.SectionMeta{Source: "spot-hinta.fi", FetchedAt: now},
 		CurrentPrice: currentPrice,
 		Prices:       priceList,
+		Days:         dailyStats(all),
 		Timestamp:    now,
 	})
 
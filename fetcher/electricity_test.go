@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"rasp_info/config"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+// spotPrices returns a spot-hinta.fi style response with 15 minute slots
+// starting at start, priced in €/kWh
+func spotPrices(t *testing.T, start time.Time, prices ...float64) []byte {
+	t.Helper()
+	var out []SpotPrice
+	for i, p := range prices {
+		out = append(out, SpotPrice{
+			PriceWithTax: p,
+			DateTime:     start.Add(time.Duration(i) * 15 * time.Minute).Format(time.RFC3339),
+		})
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestElectricityFetcher(t *testing.T) {
+	now := time.Now()
+	slot := now.Truncate(15 * time.Minute)
+
+	tests := []struct {
+		name        string
+		status      int
+		body        []byte
+		wantErr     bool
+		wantCurrent float64 // c/kWh
+		wantSlots   int
+	}{
+		{
+			name:        "past slots dropped",
+			status:      http.StatusOK,
+			body:        spotPrices(t, slot.Add(-30*time.Minute), 0.01, 0.02, 0.05, 0.06),
+			wantCurrent: 5,
+			wantSlots:   2,
+		},
+		{
+			name:        "limited to a day",
+			status:      http.StatusOK,
+			body:        spotPrices(t, slot, make([]float64, 200)...),
+			wantCurrent: 0,
+			wantSlots:   96,
+		},
+		{
+			name:        "future only uses first slot",
+			status:      http.StatusOK,
+			body:        spotPrices(t, slot.Add(time.Hour), 0.10, 0.20),
+			wantCurrent: 10,
+			wantSlots:   2,
+		},
+		{
+			name:      "empty",
+			status:    http.StatusOK,
+			body:      []byte(`[]`),
+			wantSlots: 0,
+		},
+		{
+			name:    "bad json",
+			status:  http.StatusOK,
+			body:    []byte(`{"prices":`),
+			wantErr: true,
+		},
+		{
+			name:    "not found",
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.status, tt.body)
+			st := store.New()
+			cfg := testConfig(func(c *config.Config) {
+				c.SpotAPIUrl = srv.URL
+			})
+			f := &ElectricityFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+			err := f.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			e := st.Get().Electricity
+			if len(e.Prices) != tt.wantSlots {
+				t.Errorf("got %d slots, want %d", len(e.Prices), tt.wantSlots)
+			}
+			if diff := e.CurrentPrice - tt.wantCurrent; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("current price = %v, want %v", e.CurrentPrice, tt.wantCurrent)
+			}
+			if (len(e.Days) > 0) != (tt.wantSlots > 0) {
+				t.Errorf("days = %+v with %d slots", e.Days, tt.wantSlots)
+			}
+			for _, p := range e.Prices {
+				if p.Band == "" {
+					t.Errorf("slot %s has no band", p.StartTime)
+				}
+				if p.EndTime.Sub(p.StartTime) != 15*time.Minute {
+					t.Errorf("slot %s-%s is not 15 minutes", p.StartTime, p.EndTime)
+				}
+			}
+		})
+	}
+}
@@ -1,12 +1,17 @@
 package fetcher
 
-/*
 import (
-	"rasp_info/config"
-	"rasp_info/store"
+	"context"
+	"errors"
 )
-*/
 
+// Fetcher retrieves data from one upstream source and writes it to the store.
+// Implementations must abort outstanding requests when ctx is cancelled.
 type Fetcher interface {
-	Fetch() error
+	Fetch(ctx context.Context) error
 }
+
+// ErrNotReady is returned, possibly wrapped, by a fetcher that needs data
+// another source has not fetched yet. The scheduler retries it soon without
+// counting a failure.
+var ErrNotReady = errors.New("waiting for other sources")
@@ -1,183 +1,285 @@
 package fetcher
 
 import (
-	"encoding/xml"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log"
 	"math"
-	"net/http"
 	"net/url"
 	"rasp_info/config"
 	"rasp_info/store"
-	"strings"
+	"slices"
 	"time"
 )
 
 type FMIFetcher struct {
-	Config *config.Config
+	Config *config.Manager
 	Store  *store.Store
+	HTTP   *HTTPClient // Shared HTTP layer; nil uses defaults
 }
 
-// XML Structures for parsing FMI WFS response (simplified)
-type FeatureCollection struct {
-	Member []Member `xml:"member"`
-}
+// maxObservationAge is how old the latest observation may be before the
+// forecast is used for current conditions instead
+const maxObservationAge = 2 * time.Hour
 
-type Member struct {
-	PointTimeSeriesObservation PointTimeSeriesObservation `xml:"PointTimeSeriesObservation"`
+// forecastParams are requested from the forecast stored query. FMI does not
+// provide a feels-like temperature here; it is computed with FeelsLike.
+var forecastParams = []string{
+	"Temperature", "WindSpeedMS", "WindGust", "WindDirection", "Humidity",
+	"TotalCloudCover", "Precipitation1h", "Pop", "WeatherSymbol3",
 }
 
-type PointTimeSeriesObservation struct {
-	Result Result `xml:"result"`
-}
+// observationParams are requested from the observation stored query
+var observationParams = []string{"t2m", "ws_10min", "wg_10min", "wd_10min", "rh", "r_1h"}
 
-type Result struct {
-	MeasurementTimeseries MeasurementTimeseries `xml:"MeasurementTimeseries"`
-}
+func (f *FMIFetcher) Fetch(ctx context.Context) error {
+	cfg := f.Config.Get()
+	now := time.Now().UTC()
 
-type MeasurementTimeseries struct {
-	ID    string           `xml:"id,attr"` // To distinguish between parameters
-	Point []MeasurementTVP `xml:"point"`
-}
+	// A location that fails keeps its previous data, marked with the error
+	previous := make(map[string]store.LocationWeather)
+	for _, lw := range f.Store.Get().Weather.Locations {
+		previous[lw.Name] = lw
+	}
 
-type MeasurementTVP struct {
-	MeasurementTVP struct {
-		Time  string  `xml:"time"`
-		Value float64 `xml:"value"`
-	} `xml:"MeasurementTVP"`
-}
+	weather := store.WeatherData{SectionMeta: store.SectionMeta{Source: "fmi"}}
+	var errs []error
+	for _, place := range cfg.WeatherPlaces() {
+		lw, err := f.fetchLocation(ctx, cfg, place, now)
+		if err != nil {
+			log.Printf("FMI: %s: %v", place.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", place.Name, err))
+			lw = previous[place.Name]
+			lw.Name = place.Name
+			lw.Error = err.Error()
+		}
+		weather.Locations = append(weather.Locations, lw)
+	}
+	if len(errs) == len(weather.Locations) {
+		return errors.Join(errs...)
+	}
 
-func (f *FMIFetcher) Fetch() error {
-	// Build URL
-	// https://opendata.fmi.fi/wfs?service=WFS&version=2.0.0&request=getFeature&storedquery_id=fmi::forecast::harmonie::surface::point::timevaluepair&place=Espoo&timestep=60&parameters=temperature,Precipitation1h&starttime=...&endtime=...
+	f.Store.UpdateWeather(weather)
+	return nil
+}
 
-	now := time.Now().UTC()
-	endTime := now.Add(24 * time.Hour)
-
-	baseURL, _ := url.Parse(f.Config.FMIAPIUrl)
-	params := url.Values{}
-	params.Add("service", "WFS")
-	params.Add("version", "2.0.0")
-	params.Add("request", "getFeature")
-	params.Add("storedquery_id", "fmi::forecast::harmonie::surface::point::timevaluepair")
-	params.Add("place", f.Config.WeatherLocation)
-	params.Add("timestep", "60")
-	params.Add("parameters", "temperature,Precipitation1h,Pop")
-	params.Add("starttime", now.Format(time.RFC3339))
-	params.Add("endtime", endTime.Format(time.RFC3339))
-
-	baseURL.RawQuery = params.Encode()
-
-	resp, err := http.Get(baseURL.String())
+// fetchLocation fetches the forecast and latest observation for one location
+func (f *FMIFetcher) fetchLocation(ctx context.Context, cfg *config.Config, place config.WeatherPlace, now time.Time) (store.LocationWeather, error) {
+	series, err := newFMIClient(cfg, f.HTTP).Query(ctx, WFSQuery{
+		StoredQuery: ForecastQuery,
+		Location:    locationParams(place),
+		Parameters:  forecastParams,
+		Start:       now,
+		End:         now.Add(24 * time.Hour),
+		Timestep:    time.Hour,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch FMI data: %w", err)
+		return store.LocationWeather{}, err
 	}
-	defer resp.Body.Close()
+	ts := first(series)
+	if len(ts.Times) == 0 {
+		// Keep the previous forecast rather than showing none
+		return store.LocationWeather{}, errors.New("forecast response has no data")
+	}
+	forecast, current := buildForecast(ts, now)
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("FMI api returned status: %d", resp.StatusCode)
+	lw := store.LocationWeather{
+		Name:          place.Name,
+		Lat:           ts.Location.Lat,
+		Lon:           ts.Location.Lon,
+		Current:       current,
+		CurrentSource: "forecast",
+		Forecast:      forecast,
 	}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read FMI body: %w", err)
+	obs, err := f.fetchObservation(ctx, cfg, place, now)
+	switch {
+	case err != nil:
+		log.Printf("FMI: %s: Observations unavailable, using forecast: %v", place.Name, err)
+	case obs == nil:
+		log.Printf("FMI: %s: No recent observations, using forecast", place.Name)
+	default:
+		lw.Observation = obs
+		lw.Current = withObservation(current, obs)
+		lw.CurrentSource = "observation"
+	}
+	return lw, nil
+}
+
+// fetchObservation returns the latest observation at the location's station,
+// or nil if there is none within maxObservationAge
+func (f *FMIFetcher) fetchObservation(ctx context.Context, cfg *config.Config, place config.WeatherPlace, now time.Time) (*store.WeatherObservation, error) {
+	location := locationParams(place)
+	switch {
+	case isFMISID(place.Station):
+		location = url.Values{"fmisid": {place.Station}}
+	case place.Station != "":
+		location = url.Values{"place": {place.Station}}
 	}
+	location.Set("maxlocations", "1")
 
-	// Log raw XML for debugging
-	// log.Printf("FMI Response: %s", string(bodyBytes))
+	series, err := newFMIClient(cfg, f.HTTP).Query(ctx, WFSQuery{
+		StoredQuery: ObservationQuery,
+		Location:    location,
+		Parameters:  observationParams,
+		Start:       now.Add(-maxObservationAge),
+		End:         now,
+		Timestep:    10 * time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return latestObservation(first(series)), nil
+}
 
-	var collection FeatureCollection
-	if err := xml.Unmarshal(bodyBytes, &collection); err != nil {
-		return fmt.Errorf("failed to decode FMI XML: %w", err)
+// locationParams selects the location of a stored query
+func locationParams(place config.WeatherPlace) url.Values {
+	switch {
+	case place.FMISID != "":
+		return url.Values{"fmisid": {place.FMISID}}
+	case place.LatLon != "":
+		return url.Values{"latlon": {place.LatLon}}
 	}
+	return url.Values{"place": {place.Place}}
+}
 
-	// Process data
+// buildForecast converts forecast timeseries to data points and picks the
+// one closest to now as current
+func buildForecast(ts *Timeseries, now time.Time) ([]store.WeatherDataPoint, store.WeatherDataPoint) {
 	var forecast []store.WeatherDataPoint
 	var current store.WeatherDataPoint
+	for _, t := range ts.Times {
+		wp := store.WeatherDataPoint{
+			Temperature:   optional(ts.Value("Temperature", t)),
+			WindSpeed:     optional(ts.Value("WindSpeedMS", t)),
+			WindGust:      optional(ts.Value("WindGust", t)),
+			WindDirection: optional(ts.Value("WindDirection", t)),
+			Humidity:      optional(ts.Value("Humidity", t)),
+			CloudCover:    optional(ts.Value("TotalCloudCover", t)),
+			Precipitation: optional(ts.Value("Precipitation1h", t)),
+			Pop:           optional(ts.Value("Pop", t)),
+			Time:          t,
+		}
+		wp.FeelsLike = feelsLike(wp.Temperature, wp.WindSpeed, wp.Humidity)
 
-	// Maps to hold data by time
-	temps := make(map[time.Time]float64)
-	precips := make(map[time.Time]float64)
-	pops := make(map[time.Time]float64)
-	times := make(map[time.Time]bool)
+		if code := ts.Value("WeatherSymbol3", t); !math.IsNaN(code) {
+			wp.SymbolCode = int(code)
+			wp.Symbol = WeatherSymbol(wp.SymbolCode, isNight(t, ts.Location.Lat, ts.Location.Lon))
+		}
+		if wp.Symbol == "" {
+			// Missing values are NaN and count as no rain
+			wp.Symbol = fallbackSymbol(ts.Value("Precipitation1h", t), ts.Value("Pop", t))
+		}
 
-	for _, member := range collection.Member {
-		ts := member.PointTimeSeriesObservation.Result.MeasurementTimeseries
+		forecast = append(forecast, wp)
 
-		if strings.Contains(ts.ID, "temperature") {
-			for _, p := range ts.Point {
-				t, err := time.Parse(time.RFC3339, p.MeasurementTVP.Time)
-				if err != nil {
-					continue
-				}
-				temps[t] = p.MeasurementTVP.Value
-				times[t] = true
-			}
-		} else if strings.Contains(ts.ID, "Precipitation1h") {
-			for _, p := range ts.Point {
-				t, err := time.Parse(time.RFC3339, p.MeasurementTVP.Time)
-				if err != nil {
-					continue
-				}
-				precips[t] = p.MeasurementTVP.Value
-				times[t] = true
-			}
-		} else if strings.Contains(ts.ID, "Pop") {
-			for _, p := range ts.Point {
-				t, err := time.Parse(time.RFC3339, p.MeasurementTVP.Time)
-				if err != nil {
-					continue
-				}
-				pops[t] = p.MeasurementTVP.Value
-				times[t] = true
-			}
+		// Find current (closest to now)
+		if current.Time.IsZero() || absDiff(t, now) < absDiff(current.Time, now) {
+			current = wp
 		}
 	}
+	return forecast, current
+}
 
-	// Combine data
-	for t := range times {
-		temp, okT := temps[t]
-		precip, okP := precips[t]
-		pop, okPop := pops[t]
+// latestObservation returns the most recent observation with a temperature,
+// or nil if there is none
+func latestObservation(ts *Timeseries) *store.WeatherObservation {
+	times := slices.Clone(ts.Times)
+	slices.Reverse(times)
 
-		if !okT || math.IsNaN(temp) {
-			temp = 0
+	for _, t := range times {
+		if math.IsNaN(ts.Value("t2m", t)) {
+			continue
 		}
-		if !okP || math.IsNaN(precip) {
-			precip = 0
+		// Other parameters may lag; take the latest value of each
+		latest := func(param string) *float64 {
+			for _, t2 := range times {
+				if t2.After(t) {
+					continue
+				}
+				if v := ts.Value(param, t2); !math.IsNaN(v) {
+					return &v
+				}
+			}
+			return nil
 		}
-		if !okPop || math.IsNaN(pop) {
-			pop = 0
+		return &store.WeatherObservation{
+			Station:       ts.Location.Name,
+			StationID:     ts.Location.StationID,
+			Time:          t,
+			Temperature:   latest("t2m"),
+			WindSpeed:     latest("ws_10min"),
+			WindGust:      latest("wg_10min"),
+			WindDirection: latest("wd_10min"),
+			Humidity:      latest("rh"),
+			Precipitation: latest("r_1h"),
 		}
+	}
+	return nil
+}
 
-		// Determine symbol
-		symbol := "cloudy"
-		if precip > 0.1 || pop > 50 {
-			symbol = "rain"
+// withObservation replaces forecast values in current with observed ones.
+// Symbol, cloud cover and probability of precipitation stay from the forecast.
+func withObservation(current store.WeatherDataPoint, obs *store.WeatherObservation) store.WeatherDataPoint {
+	set := func(dst **float64, v *float64) {
+		if v != nil {
+			*dst = v
 		}
+	}
+	set(&current.Temperature, obs.Temperature)
+	set(&current.WindSpeed, obs.WindSpeed)
+	set(&current.WindGust, obs.WindGust)
+	set(&current.WindDirection, obs.WindDirection)
+	set(&current.Humidity, obs.Humidity)
+	set(&current.Precipitation, obs.Precipitation)
+	current.FeelsLike = feelsLike(current.Temperature, current.WindSpeed, current.Humidity)
+	current.Time = obs.Time
+	return current
+}
 
-		wp := store.WeatherDataPoint{
-			Temperature:   temp,
-			Precipitation: precip,
-			Pop:           pop,
-			Symbol:        symbol,
-			Time:          t,
+// isFMISID reports whether s is a numeric FMI station id
+func isFMISID(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
 		}
+	}
+	return true
+}
 
-		forecast = append(forecast, wp)
-
-		// Find current (closest to now)
-		if current.Time.IsZero() || absDiff(t, now) < absDiff(current.Time, now) {
-			current = wp
-		}
+// first returns the timeseries of the first location, or an empty one if
+// the response had none. An empty forecast is an error; empty observations
+// only mean the station has not reported.
+func first(series []*Timeseries) *Timeseries {
+	if len(series) == 0 {
+		return &Timeseries{}
 	}
+	return series[0]
+}
 
-	f.Store.UpdateWeather(store.WeatherData{
-		Current:  current,
-		Forecast: forecast,
-	})
+// optional returns v, or nil if it is missing (NaN)
+func optional(v float64) *float64 {
+	if math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
 
-	return nil
+// feelsLike is FeelsLike for optional values. It is nil without temperature
+// or wind; missing humidity only leaves out the summer simmer term.
+func feelsLike(temp, windSpeed, humidity *float64) *float64 {
+	if temp == nil || windSpeed == nil {
+		return nil
+	}
+	rh := math.NaN()
+	if humidity != nil {
+		rh = *humidity
+	}
+	return optional(FeelsLike(*temp, *windSpeed, rh))
 }
 
 func absDiff(a, b time.Time) time.Duration {
@@ -0,0 +1,297 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rasp_info/config"
+	"rasp_info/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fmiResponse is a canned answer to one stored query
+type fmiResponse struct {
+	status int
+	body   []byte
+}
+
+// newFMIServer answers forecast and observation queries separately and
+// records the query strings it received
+func newFMIServer(t *testing.T, forecast, observations fmiResponse) (*httptest.Server, *[]string) {
+	t.Helper()
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		resp := forecast
+		if strings.Contains(r.URL.Query().Get("storedquery_id"), "observations") {
+			resp = observations
+		}
+		w.WriteHeader(resp.status)
+		w.Write(resp.body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &queries
+}
+
+func ptr(v float64) *float64 { return &v }
+
+// sameValue reports whether two optional values are both missing or equal
+func sameValue(a, b *float64) bool {
+	return a == nil && b == nil || a != nil && b != nil && *a == *b
+}
+
+func TestFMIFetcher(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	forecastOK := fmiResponse{http.StatusOK, readTestdata(t, "fmi_forecast.xml")}
+	noObservations := fmiResponse{http.StatusOK, []byte(`<wfs:FeatureCollection xmlns:wfs="http://www.opengis.net/wfs/2.0"/>`)}
+
+	tests := []struct {
+		name         string
+		forecast     fmiResponse
+		observations fmiResponse
+		wantErr      bool
+		want         map[time.Time]store.WeatherDataPoint // Checked fields: temperature, precipitation, pop, symbol
+		wantSource   string
+		check        func(t *testing.T, w store.LocationWeather)
+	}{
+		{
+			name:         "forecast",
+			forecast:     forecastOK,
+			observations: noObservations,
+			want: map[time.Time]store.WeatherDataPoint{
+				at("2026-10-16T10:00:00Z"): {Temperature: ptr(7.5), Precipitation: ptr(0), Pop: ptr(10), Symbol: "cloudy"},
+				at("2026-10-16T11:00:00Z"): {Temperature: ptr(8.1), Precipitation: ptr(0.6), Pop: ptr(80), Symbol: "rain"},
+				// NaN temperature is left out, not reported as 0
+				at("2026-10-16T12:00:00Z"): {Precipitation: ptr(0), Pop: ptr(60), Symbol: "rain"},
+			},
+			wantSource: "forecast",
+			check: func(t *testing.T, w store.LocationWeather) {
+				if w.Name != "Tapiola" || w.Lat != 60.17482 || w.Lon != 24.80515 {
+					t.Errorf("location = %q at %v,%v", w.Name, w.Lat, w.Lon)
+				}
+				// Parameters missing from the response are left out too
+				for _, p := range w.Forecast {
+					if p.WindSpeed != nil || p.Humidity != nil || p.CloudCover != nil || p.FeelsLike != nil {
+						t.Errorf("point at %s has values FMI did not send: %+v", p.Time, p)
+					}
+				}
+				last, err := json.Marshal(w.Forecast[len(w.Forecast)-1])
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, field := range []string{"temperature", "wind_speed", "feels_like"} {
+					if strings.Contains(string(last), `"`+field+`"`) {
+						t.Errorf("JSON %s contains missing %s", last, field)
+					}
+				}
+			},
+		},
+		{
+			name:         "observations",
+			forecast:     forecastOK,
+			observations: fmiResponse{http.StatusOK, readTestdata(t, "fmi_observations.xml")},
+			want:         nil, // Same forecast as above
+			wantSource:   "observation",
+			check: func(t *testing.T, w store.LocationWeather) {
+				obs := w.Observation
+				if obs == nil {
+					t.Fatal("observation missing")
+				}
+				if obs.Station != "Helsinki Kaisaniemi" || obs.StationID != "100971" {
+					t.Errorf("station = %q (%q)", obs.Station, obs.StationID)
+				}
+				// 10:10 has no temperature yet, so 10:00 is the latest observation
+				if !obs.Time.Equal(at("2026-10-16T10:00:00Z")) || *obs.Temperature != 7.2 {
+					t.Errorf("observation = %s %v", obs.Time, *obs.Temperature)
+				}
+				// Wind lags behind and comes from 09:50; humidity is missing
+				if obs.WindSpeed == nil || *obs.WindSpeed != 3.4 || obs.Humidity != nil {
+					t.Errorf("wind = %v, humidity = %v", obs.WindSpeed, obs.Humidity)
+				}
+				if !sameValue(w.Current.Temperature, ptr(7.2)) || !sameValue(w.Current.WindSpeed, ptr(3.4)) || w.Current.FeelsLike == nil {
+					t.Errorf("current = %+v, want observed values", w.Current)
+				}
+			},
+		},
+		{
+			name:         "observation query fails",
+			forecast:     forecastOK,
+			observations: fmiResponse{http.StatusBadRequest, []byte(`<ExceptionReport/>`)},
+			wantSource:   "forecast",
+		},
+		{
+			name:         "no members",
+			forecast:     noObservations,
+			observations: noObservations,
+			wantErr:      true,
+		},
+		{
+			name:     "server error",
+			forecast: fmiResponse{http.StatusInternalServerError, nil},
+			wantErr:  true,
+		},
+		{
+			name:     "not xml",
+			forecast: fmiResponse{http.StatusOK, []byte(`{"error":"nope"}`)},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, queries := newFMIServer(t, tt.forecast, tt.observations)
+			st := store.New()
+			cfg := testConfig(func(c *config.Config) {
+				c.FMIAPIUrl = srv.URL + "/wfs"
+				c.WeatherLocation = "Tapiola"
+				c.WeatherStation = "100971"
+			})
+			f := &FMIFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+			err := f.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(*queries) != 2 || !strings.Contains((*queries)[0], "place=Tapiola") || !strings.Contains((*queries)[1], "fmisid=100971") {
+				t.Errorf("queries = %v", *queries)
+			}
+
+			data := st.Get().Weather
+			if data.Source != "fmi" || len(data.Locations) != 1 {
+				t.Fatalf("source = %q, %d locations", data.Source, len(data.Locations))
+			}
+			w := data.Locations[0]
+			if w.CurrentSource != tt.wantSource {
+				t.Errorf("current from %q, want %q", w.CurrentSource, tt.wantSource)
+			}
+			if tt.want != nil {
+				if len(w.Forecast) != len(tt.want) {
+					t.Fatalf("got %d forecast points, want %d", len(w.Forecast), len(tt.want))
+				}
+				for _, p := range w.Forecast {
+					want, ok := tt.want[p.Time]
+					if !ok {
+						t.Errorf("unexpected point at %s", p.Time)
+						continue
+					}
+					if !sameValue(p.Temperature, want.Temperature) || !sameValue(p.Precipitation, want.Precipitation) || !sameValue(p.Pop, want.Pop) || p.Symbol != want.Symbol {
+						t.Errorf("point at %s = %+v, want %+v", p.Time, p, want)
+					}
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, w)
+			}
+		})
+	}
+}
+
+func TestWithObservation(t *testing.T) {
+	forecast := store.WeatherDataPoint{Temperature: ptr(24), WindSpeed: ptr(3), Humidity: ptr(80), CloudCover: ptr(40)}
+	// Many stations do not measure humidity
+	obs := &store.WeatherObservation{Temperature: ptr(26), WindSpeed: ptr(1), Time: time.Now()}
+
+	got := withObservation(forecast, obs)
+	if !sameValue(got.Temperature, ptr(26)) || !sameValue(got.WindSpeed, ptr(1)) || !sameValue(got.Humidity, ptr(80)) || !sameValue(got.CloudCover, ptr(40)) {
+		t.Errorf("current = %+v, want observed temperature and wind with forecast humidity and clouds", got)
+	}
+	// Feels-like uses the humidity shown, so summer simmer still applies
+	if want := FeelsLike(26, 1, 80); !sameValue(got.FeelsLike, &want) {
+		t.Errorf("feels like = %v, want %.2f", got.FeelsLike, want)
+	}
+}
+
+func TestFMIFetcherEmptyForecastKeepsPrevious(t *testing.T) {
+	srv, _ := newFMIServer(t,
+		fmiResponse{http.StatusOK, []byte(`<wfs:FeatureCollection xmlns:wfs="http://www.opengis.net/wfs/2.0"/>`)},
+		fmiResponse{http.StatusOK, readTestdata(t, "fmi_observations.xml")})
+	st := store.New()
+	previous := []store.WeatherDataPoint{{Temperature: ptr(5), Time: time.Now()}}
+	st.UpdateWeather(store.WeatherData{Locations: []store.LocationWeather{{Name: "Tapiola", Forecast: previous}}})
+	cfg := testConfig(func(c *config.Config) {
+		c.FMIAPIUrl = srv.URL + "/wfs"
+		c.WeatherLocation = "Tapiola"
+	})
+	f := &FMIFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+	if err := f.Fetch(context.Background()); err == nil {
+		t.Fatal("Fetch() of an empty forecast succeeded")
+	}
+	locations := st.Get().Weather.Locations
+	if len(locations) != 1 || len(locations[0].Forecast) != 1 || !sameValue(locations[0].Forecast[0].Temperature, ptr(5)) {
+		t.Errorf("locations = %+v, want the previous forecast", locations)
+	}
+}
+
+func TestFMIFetcherLocations(t *testing.T) {
+	var queries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch {
+		case r.URL.Query().Get("latlon") != "":
+			w.WriteHeader(http.StatusInternalServerError)
+		case strings.Contains(r.URL.Query().Get("storedquery_id"), "observations"):
+			w.Write(readTestdata(t, "fmi_observations.xml"))
+		default:
+			w.Write(readTestdata(t, "fmi_forecast.xml"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	st := store.New()
+	st.UpdateWeather(store.WeatherData{Locations: []store.LocationWeather{
+		{Name: "Cabin", CurrentSource: "forecast", Current: store.WeatherDataPoint{Temperature: ptr(3)}},
+	}})
+	cfg := testConfig(func(c *config.Config) {
+		c.FMIAPIUrl = srv.URL + "/wfs"
+		c.WeatherLocations = []config.WeatherPlace{
+			{Name: "Home", FMISID: "100971"},
+			{Name: "Cabin", LatLon: "61.05,28.19"},
+		}
+	})
+	f := &FMIFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+	if err := f.Fetch(context.Background()); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	// Forecast and observations for Home, then the failing forecast for Cabin
+	if len(queries) != 3 || !strings.Contains(queries[0], "fmisid=100971") || !strings.Contains(queries[2], "latlon=61.05%2C28.19") {
+		t.Errorf("queries = %v", queries)
+	}
+
+	locations := st.Get().Weather.Locations
+	if len(locations) != 2 {
+		t.Fatalf("got %d locations, want 2", len(locations))
+	}
+	home, cabin := locations[0], locations[1]
+	if home.Name != "Home" || home.CurrentSource != "observation" || home.Error != "" {
+		t.Errorf("home = %q from %q, error %q", home.Name, home.CurrentSource, home.Error)
+	}
+	// The failed location keeps its previous data
+	if cabin.Name != "Cabin" || !sameValue(cabin.Current.Temperature, ptr(3)) || cabin.Error == "" {
+		t.Errorf("cabin = %q at %v, error %q", cabin.Name, cabin.Current.Temperature, cabin.Error)
+	}
+
+	// All locations failing fails the fetch
+	cfg = testConfig(func(c *config.Config) {
+		c.FMIAPIUrl = srv.URL + "/wfs"
+		c.WeatherLocations = []config.WeatherPlace{{Name: "Cabin", LatLon: "61.05,28.19"}}
+	})
+	f = &FMIFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+	if err := f.Fetch(context.Background()); err == nil {
+		t.Error("Fetch() with every location failing succeeded")
+	}
+}
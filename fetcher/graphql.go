@@ -0,0 +1,229 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rasp_info/config"
+	"sort"
+	"strings"
+)
+
+// Error kinds reported by the Digitransit API. Use errors.Is on errors
+// returned by GraphQLClient.
+var (
+	ErrAuth        = errors.New("digitransit: authentication failed")
+	ErrRateLimited = errors.New("digitransit: rate limited")
+	ErrUnknownStop = errors.New("digitransit: unknown stop")
+	ErrGraphQL     = errors.New("digitransit: query error")
+)
+
+// GraphQLError is one entry of the "errors" array in a GraphQL response
+type GraphQLError struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Alias returns the top-level field the error belongs to, if any
+func (e GraphQLError) Alias() string {
+	if len(e.Path) == 0 {
+		return ""
+	}
+	s, _ := e.Path[0].(string)
+	return s
+}
+
+// APIError is returned when Digitransit rejects a request or reports GraphQL
+// errors. Partial is set if data was returned alongside the errors, in which
+// case the decoded result is usable for the fields that did succeed.
+type APIError struct {
+	Kind    error // One of the Err* values above
+	Status  int   // HTTP status
+	Errors  []GraphQLError
+	Partial bool
+}
+
+func (e *APIError) Error() string {
+	var msgs []string
+	for _, ge := range e.Errors {
+		msgs = append(msgs, ge.Message)
+	}
+	if len(msgs) == 0 {
+		return fmt.Sprintf("%v (status %d)", e.Kind, e.Status)
+	}
+	return fmt.Sprintf("%v: %s", e.Kind, strings.Join(msgs, "; "))
+}
+
+func (e *APIError) Unwrap() error { return e.Kind }
+
+// IsPartial reports whether err is an APIError that still carried data
+func IsPartial(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Partial
+}
+
+// GraphQLClient sends queries to the Digitransit routing API
+type GraphQLClient struct {
+	URL  string
+	Key  string
+	HTTP *HTTPClient
+}
+
+// newDigitransitClient returns a client for the configured routing API
+func newDigitransitClient(cfg *config.Config, h *HTTPClient) *GraphQLClient {
+	return &GraphQLClient{
+		URL:  cfg.HSLAPIUrl,
+		Key:  cfg.HSLKey,
+		HTTP: h,
+	}
+}
+
+// Do runs a query with variables and decodes its "data" into out
+func (c *GraphQLClient) Do(ctx context.Context, query string, variables map[string]any, out any) error {
+	reqBody, err := json.Marshal(map[string]any{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode graphql request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.URL, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to create graphql request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("digitransit-subscription-key", c.Key)
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("graphql request failed: %w", err)
+	}
+
+	// Error responses may carry an "errors" array too, e.g. a 400 for an
+	// invalid query, so decode it before looking at the status
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []GraphQLError  `json:"errors"`
+	}
+	decodeErr := json.Unmarshal(resp.Body, &result)
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp.StatusCode, result.Errors)
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("failed to decode graphql response: %w", decodeErr)
+	}
+
+	hasData := len(result.Data) > 0 && string(result.Data) != "null"
+	if hasData && out != nil {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return fmt.Errorf("failed to decode graphql data: %w", err)
+		}
+	}
+	if len(result.Errors) > 0 {
+		return &APIError{
+			Kind:    classify(result.Errors),
+			Status:  resp.StatusCode,
+			Errors:  result.Errors,
+			Partial: hasData,
+		}
+	}
+	return nil
+}
+
+// statusError is the error for a non-200 response, keeping any GraphQL
+// errors it carried
+func statusError(status int, errs []GraphQLError) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return &APIError{Kind: ErrAuth, Status: status, Errors: errs}
+	case status == http.StatusTooManyRequests:
+		return &APIError{Kind: ErrRateLimited, Status: status, Errors: errs}
+	case len(errs) > 0:
+		return &APIError{Kind: classify(errs), Status: status, Errors: errs}
+	}
+	return fmt.Errorf("digitransit api returned status: %d", status)
+}
+
+// classify picks the most specific error kind for a set of GraphQL errors
+func classify(errs []GraphQLError) error {
+	for _, e := range errs {
+		msg := strings.ToLower(e.Message)
+		code, _ := e.Extensions["code"].(string)
+		switch {
+		case code == "UNAUTHENTICATED" || strings.Contains(msg, "unauthorized") || strings.Contains(msg, "subscription key"):
+			return ErrAuth
+		case strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests"):
+			return ErrRateLimited
+		case strings.Contains(msg, "stop") && (strings.Contains(msg, "not found") || strings.Contains(msg, "invalid")):
+			return ErrUnknownStop
+		}
+	}
+	return ErrGraphQL
+}
+
+// QueryBuilder assembles one GraphQL query out of several aliased fields,
+// passing every value as a variable instead of interpolating it
+type QueryBuilder struct {
+	vars      []string // "$v0: String!"
+	values    map[string]any
+	fields    []string
+	fragments map[string]string
+}
+
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{
+		values:    make(map[string]any),
+		fragments: make(map[string]string),
+	}
+}
+
+// Var declares a variable of GraphQL type typ and returns its reference
+// (e.g. "$v0") for use in a field body
+func (b *QueryBuilder) Var(typ string, value any) string {
+	name := fmt.Sprintf("v%d", len(b.vars))
+	b.vars = append(b.vars, fmt.Sprintf("$%s: %s", name, typ))
+	b.values[name] = value
+	return "$" + name
+}
+
+// Field adds an aliased top-level field, e.g. Field("stop0", `stop(id: $v0) { name }`)
+func (b *QueryBuilder) Field(alias, body string) {
+	b.fields = append(b.fields, fmt.Sprintf("  %s: %s", alias, strings.TrimSpace(body)))
+}
+
+// Fragment adds a named fragment definition; adding the same name twice is a no-op
+func (b *QueryBuilder) Fragment(name, definition string) {
+	b.fragments[name] = strings.TrimSpace(definition)
+}
+
+// Empty reports whether no fields were added
+func (b *QueryBuilder) Empty() bool {
+	return len(b.fields) == 0
+}
+
+// Build returns the query text and its variables
+func (b *QueryBuilder) Build() (string, map[string]any) {
+	var sb strings.Builder
+	sb.WriteString("query")
+	if len(b.vars) > 0 {
+		sb.WriteString("(" + strings.Join(b.vars, ", ") + ")")
+	}
+	sb.WriteString(" {\n")
+	sb.WriteString(strings.Join(b.fields, "\n"))
+	sb.WriteString("\n}\n")
+
+	names := make([]string, 0, len(b.fragments))
+	for name := range b.fragments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sb.WriteString(b.fragments[name] + "\n")
+	}
+	return sb.String(), b.values
+}
@@ -0,0 +1,105 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGraphQLClientErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantKind    error // nil for a plain error, or no error if wantMsg is empty too
+		wantMsg     string
+		wantPartial bool
+	}{
+		{name: "ok", status: http.StatusOK, body: `{"data":{"stop":{"name":"Kamppi"}}}`},
+		{
+			name:     "bad request with errors",
+			status:   http.StatusBadRequest,
+			body:     `{"errors":[{"message":"Validation error of type FieldUndefined: Field 'foo' in type 'Stop' is undefined"}]}`,
+			wantKind: ErrGraphQL,
+			wantMsg:  "Field 'foo' in type 'Stop' is undefined",
+		},
+		{
+			name:     "bad request for unknown stop",
+			status:   http.StatusBadRequest,
+			body:     `{"errors":[{"message":"Stop 'HSL:0' not found"}]}`,
+			wantKind: ErrUnknownStop,
+			wantMsg:  "Stop 'HSL:0' not found",
+		},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `Access denied`, wantKind: ErrAuth, wantMsg: "status 401"},
+		{
+			name:     "forbidden with errors",
+			status:   http.StatusForbidden,
+			body:     `{"errors":[{"message":"Invalid subscription key"}]}`,
+			wantKind: ErrAuth,
+			wantMsg:  "Invalid subscription key",
+		},
+		{name: "rate limited", status: http.StatusTooManyRequests, body: `{"statusCode":429}`, wantKind: ErrRateLimited, wantMsg: "status 429"},
+		{name: "server error", status: http.StatusBadGateway, body: `<html>Bad Gateway</html>`, wantMsg: "status: 502"},
+		{
+			name:     "server error with errors",
+			status:   http.StatusInternalServerError,
+			body:     `{"errors":[{"message":"Internal error: timeout"}]}`,
+			wantKind: ErrGraphQL,
+			wantMsg:  "Internal error: timeout",
+		},
+		{
+			name:        "ok with errors and data",
+			status:      http.StatusOK,
+			body:        `{"data":{"stop":{"name":"Kamppi"}},"errors":[{"message":"Rate limit exceeded","path":["other"]}]}`,
+			wantKind:    ErrRateLimited,
+			wantMsg:     "Rate limit exceeded",
+			wantPartial: true,
+		},
+		{
+			name:     "ok with errors only",
+			status:   http.StatusOK,
+			body:     `{"data":null,"errors":[{"message":"Unauthorized","extensions":{"code":"UNAUTHENTICATED"}}]}`,
+			wantKind: ErrAuth,
+			wantMsg:  "Unauthorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.status, []byte(tt.body))
+			c := &GraphQLClient{URL: srv.URL, Key: "test-key", HTTP: NewHTTPClient(testConfig(nil))}
+
+			var out struct {
+				Stop struct{ Name string }
+			}
+			err := c.Do(context.Background(), "{ stop { name } }", nil, &out)
+			if tt.wantKind == nil && tt.wantMsg == "" {
+				if err != nil || out.Stop.Name != "Kamppi" {
+					t.Fatalf("Do() = %v, stop %q", err, out.Stop.Name)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want an error")
+			}
+			var apiErr *APIError
+			if isAPI := errors.As(err, &apiErr); isAPI != (tt.wantKind != nil) {
+				t.Fatalf("error %v is APIError = %v", err, isAPI)
+			}
+			if tt.wantKind != nil && (!errors.Is(err, tt.wantKind) || apiErr.Status != tt.status) {
+				t.Errorf("error = %v (status %d), want %v (status %d)", err, apiErr.Status, tt.wantKind, tt.status)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.wantMsg)
+			}
+			if IsPartial(err) != tt.wantPartial {
+				t.Errorf("partial = %v, want %v", IsPartial(err), tt.wantPartial)
+			}
+			if tt.wantPartial && out.Stop.Name != "Kamppi" {
+				t.Errorf("partial data not decoded, stop %q", out.Stop.Name)
+			}
+		})
+	}
+}
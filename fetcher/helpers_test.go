@@ -0,0 +1,63 @@
+package fetcher
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"rasp_info/config"
+	"sync"
+	"testing"
+)
+
+// testConfig returns a static config manager based on the defaults
+func testConfig(mutate func(*config.Config)) *config.Manager {
+	cfg := config.Default()
+	cfg.HSLKey = "test-key"
+	cfg.HTTPRetries = 0
+	if mutate != nil {
+		mutate(cfg)
+	}
+	return config.Static(cfg)
+}
+
+// testServer answers every request with status and body and remembers the
+// last request it received
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	lastReq  *http.Request
+	lastBody []byte
+}
+
+func newTestServer(t *testing.T, status int, body []byte) *testServer {
+	t.Helper()
+	ts := &testServer{}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		ts.mu.Lock()
+		ts.lastReq, ts.lastBody = r, b
+		ts.mu.Unlock()
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func (ts *testServer) last() (*http.Request, []byte) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	return ts.lastReq, ts.lastBody
+}
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
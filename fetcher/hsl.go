@@ -1,150 +1,241 @@
 package fetcher
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"rasp_info/config"
 	"rasp_info/store"
 	"strings"
 	"time"
 )
 
-const stopQueryFragment = `
-  %s: stop(id: "%s") {
+// overFetchFactor is how many more departures are requested for stops with
+// route or headsign filters, so enough remain after filtering
+const (
+	overFetchFactor = 4
+	maxFetchCount   = 50
+)
+
+// stopQueryFields is the body of one aliased stop field. The placeholders
+// are variable references for the stop id, start time and departure count.
+const stopQueryFields = `
+  stop(id: %s) {
     name
-    stoptimesWithoutPatterns(numberOfDepartures: 4) {
+    code
+    stoptimesWithoutPatterns(startTime: %s, numberOfDepartures: %s) {
       scheduledDeparture
       realtimeDeparture
       departureDelay
       realtime
       realtimeState
+      pickupType
+      dropoffType
       serviceDay
       headsign
+      stop {
+        code
+        platformCode
+      }
       trip {
         route {
           shortName
+          mode
         }
       }
     }
+    alerts {
+      ...AlertFields
+    }
+    routes {
+      shortName
+      alerts {
+        ...AlertFields
+      }
+    }
   }
 `
 
+// alertFragment is added to every stop query; stop and route alerts share it
+const alertFragment = `
+fragment AlertFields on Alert {
+  id
+  alertSeverityLevel
+  alertUrl
+  effectiveStartDate
+  effectiveEndDate
+  alertHeaderText
+  alertDescriptionText
+  alertHeaderTextTranslations {
+    text
+    language
+  }
+  alertDescriptionTextTranslations {
+    text
+    language
+  }
+}
+`
+
 type HSLFetcher struct {
-	Config *config.Config
+	Config *config.Manager
 	Store  *store.Store
+	HTTP   *HTTPClient // Shared HTTP layer; nil uses defaults
+	Stops  *StopCache  // Optional; without it short codes are looked up on every fetch
 }
 
 type StopResponse struct {
 	Name      string `json:"name"`
+	Code      string `json:"code"`
 	Stoptimes []struct {
 		ScheduledDeparture int    `json:"scheduledDeparture"`
 		RealtimeDeparture  int    `json:"realtimeDeparture"`
+		DepartureDelay     int    `json:"departureDelay"` // Seconds, negative if early
 		Realtime           bool   `json:"realtime"`
+		RealtimeState      string `json:"realtimeState"` // SCHEDULED, UPDATED, CANCELED, ADDED, MODIFIED
+		PickupType         string `json:"pickupType"`    // NONE if passengers cannot board
+		DropoffType        string `json:"dropoffType"`   // NONE if passengers cannot alight
 		ServiceDay         int    `json:"serviceDay"`
 		Headsign           string `json:"headsign"`
-		Trip               struct {
+		Stop               struct {
+			Code         string `json:"code"`
+			PlatformCode string `json:"platformCode"`
+		} `json:"stop"`
+		Trip struct {
 			Route struct {
 				ShortName string `json:"shortName"`
+				Mode      string `json:"mode"`
 			} `json:"route"`
 		} `json:"trip"`
 	} `json:"stoptimesWithoutPatterns"`
+	Alerts []AlertResponse `json:"alerts"`
+	Routes []struct {
+		ShortName string          `json:"shortName"`
+		Alerts    []AlertResponse `json:"alerts"`
+	} `json:"routes"`
 }
 
-// HSLResponse now maps dynamic keys to StopResponse
-type HSLResponse struct {
-	Data map[string]StopResponse `json:"data"`
+type TranslatedText struct {
+	Text     string `json:"text"`
+	Language string `json:"language"`
 }
 
-func (f *HSLFetcher) Fetch() error {
-	log.Println("Starting HSL fetch...")
-
-	// Build dynamic query
-	var queryBuilder bytes.Buffer
-	queryBuilder.WriteString("{\n")
-
-	// Map to store resolved IDs to original config index
-	resolvedIDs := make(map[string]int)
-
-	for i, stop := range f.Config.BusStops {
-		stopID := stop.ID
-		// Check if ID needs resolution (e.g. E1234-style code or plain code)
-		if !strings.HasPrefix(stopID, "HSL:") {
-			log.Printf("Resolving stop code via geocoding API: %s", stopID)
-			id, err := f.LookupStop(stopID)
-			if err != nil {
-				log.Printf("Failed to resolve stop %s: %v", stopID, err)
-				continue
-			}
-			log.Printf("Resolved %s to GTFS id %s", stopID, id)
-			stopID = id
-		}
+type AlertResponse struct {
+	ID                               string           `json:"id"`
+	AlertSeverityLevel               string           `json:"alertSeverityLevel"`
+	AlertURL                         string           `json:"alertUrl"`
+	EffectiveStartDate               int64            `json:"effectiveStartDate"` // Unix seconds
+	EffectiveEndDate                 int64            `json:"effectiveEndDate"`
+	AlertHeaderText                  string           `json:"alertHeaderText"`
+	AlertDescriptionText             string           `json:"alertDescriptionText"`
+	AlertHeaderTextTranslations      []TranslatedText `json:"alertHeaderTextTranslations"`
+	AlertDescriptionTextTranslations []TranslatedText `json:"alertDescriptionTextTranslations"`
+}
 
-		alias := fmt.Sprintf("stop%d", i)
-		queryBuilder.WriteString(fmt.Sprintf(stopQueryFragment, alias, stopID))
-		resolvedIDs[alias] = i
+func (f *HSLFetcher) Fetch(ctx context.Context) error {
+	log.Println("Starting HSL fetch...")
+	cfg := f.Config.Get()
+	if len(cfg.BusStops) == 0 {
+		f.Store.UpdateTransport(store.TransportData{SectionMeta: store.SectionMeta{Source: "digitransit"}, Timestamp: time.Now()})
+		f.Store.UpdateAlerts(store.AlertsData{SectionMeta: store.SectionMeta{Source: "digitransit"}})
+		return nil
 	}
-	queryBuilder.WriteString("\n}")
 
-	reqBody, _ := json.Marshal(map[string]string{
-		"query": queryBuilder.String(),
-	})
-
-	req, err := http.NewRequest("POST", f.Config.HSLAPIUrl, bytes.NewBuffer(reqBody))
-	if err != nil {
-		log.Printf("HSL: Error creating request: %v", err)
-		return err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("digitransit-subscription-key", f.Config.HSLKey)
+	// Build one batched query with an aliased field per stop
+	q := NewQueryBuilder()
+	q.Fragment("AlertFields", alertFragment)
+	var unresolved []error
+	for i, stop := range cfg.BusStops {
+		stopID, err := f.resolveStop(ctx, stop.ID)
+		if err != nil {
+			log.Printf("Failed to resolve stop %s: %v", stop.ID, err)
+			unresolved = append(unresolved, err)
+			continue
+		}
 
-	log.Printf("HSL: Sending request to %s", f.Config.HSLAPIUrl)
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("HSL: Request failed: %v", err)
-		return fmt.Errorf("failed to fetch HSL data: %w", err)
+		startTime, count := departureWindow(stop, time.Now())
+		q.Field(fmt.Sprintf("stop%d", i), fmt.Sprintf(stopQueryFields,
+			q.Var("String!", stopID), q.Var("Long", startTime), q.Var("Int", count)))
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("HSL: API returned status %d", resp.StatusCode)
-		return fmt.Errorf("HSL api returned status: %d", resp.StatusCode)
+	if q.Empty() {
+		return fmt.Errorf("no HSL stops could be resolved: %w", errors.Join(unresolved...))
 	}
+	query, vars := q.Build()
 
-	var result HSLResponse
-	if err := json.Unmarshal(bodyBytes(resp.Body), &result); err != nil {
-		log.Printf("HSL: Failed to decode JSON: %v", err)
-		return fmt.Errorf("failed to decode HSL json: %w", err)
+	log.Printf("HSL: Sending request to %s", cfg.HSLAPIUrl)
+	// Unknown stops come back as null
+	var stopDataMap map[string]*StopResponse
+	if err := newDigitransitClient(cfg, f.HTTP).Do(ctx, query, vars, &stopDataMap); err != nil {
+		if !IsPartial(err) {
+			log.Printf("HSL: Request failed: %v", err)
+			return fmt.Errorf("failed to fetch HSL data: %w", err)
+		}
+		log.Printf("HSL: Partial response: %v", err)
 	}
 
 	var stops []store.StopData
 
-	// Iterate over resolved IDs to maintain order or just iterate config
-	// We need to match the alias back to the config name
-
-	// Create a map of alias -> StopResponse for easier access
-	stopDataMap := result.Data
 	log.Printf("HSL: Received data for %d stops", len(stopDataMap))
 
-	for i, cfgStop := range f.Config.BusStops {
+	alerts := newAlertCollector()
+	var unknown []string
+
+	for i, cfgStop := range cfg.BusStops {
 		alias := fmt.Sprintf("stop%d", i)
-		if s, ok := stopDataMap[alias]; ok {
+		s, ok := stopDataMap[alias]
+		if ok && s == nil {
+			log.Printf("HSL: %v: %s (%s)", ErrUnknownStop, cfgStop.ID, cfgStop.Name)
+			unknown = append(unknown, cfgStop.ID)
+			continue
+		}
+		if ok {
 			var departures []store.Departure
 			for _, st := range s.Stoptimes {
+				if len(departures) >= cfgStop.DepartureCount() {
+					break
+				}
+				if !cfgStop.Wants(st.Trip.Route.ShortName, st.Headsign) {
+					continue
+				}
 				departureTime := time.Unix(int64(st.ServiceDay)+int64(st.RealtimeDeparture), 0)
+				scheduledTime := time.Unix(int64(st.ServiceDay)+int64(st.ScheduledDeparture), 0)
 				departures = append(departures, store.Departure{
-					RouteNumber: st.Trip.Route.ShortName,
-					Destination: st.Headsign,
-					Time:        departureTime,
-					Realtime:    st.Realtime,
+					RouteNumber:   st.Trip.Route.ShortName,
+					Destination:   st.Headsign,
+					Time:          departureTime,
+					ScheduledTime: scheduledTime,
+					DelaySeconds:  st.DepartureDelay,
+					Realtime:      st.Realtime,
+					RealtimeState: st.RealtimeState,
+					Cancelled:     st.RealtimeState == "CANCELED",
+					Skipped:       isSkipped(st.Realtime, st.RealtimeState, st.PickupType, st.DropoffType),
+					Platform:      st.Stop.PlatformCode,
+					StopCode:      st.Stop.Code,
+					Mode:          routeMode(st.Trip.Route.Mode),
 				})
 			}
+			name := cfgStop.Name // Use name from config
+			if name == "" {
+				name = s.Name
+			}
+			for _, a := range s.Alerts {
+				alerts.add(a, name, "")
+			}
+			for _, r := range s.Routes {
+				if !cfgStop.WantsRoute(r.ShortName) {
+					continue // No alerts for routes filtered out of this stop
+				}
+				for _, a := range r.Alerts {
+					alerts.add(a, "", r.ShortName)
+				}
+			}
 			stops = append(stops, store.StopData{
-				StopName:   cfgStop.Name, // Use name from config
+				StopName:   name,
+				StopCode:   s.Code,
 				Departures: departures,
 			})
 			log.Printf("HSL: Processed stop %s (%s): %d departures", cfgStop.Name, cfgStop.ID, len(departures))
@@ -153,39 +244,116 @@ func (f *HSLFetcher) Fetch() error {
 		}
 	}
 
+	if len(stops) == 0 && len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownStop, strings.Join(unknown, ", "))
+	}
+
 	f.Store.UpdateTransport(store.TransportData{
-		Stops:     stops,
-		Timestamp: time.Now(),
+		SectionMeta: store.SectionMeta{Source: "digitransit"},
+		Stops:       stops,
+		Timestamp:   time.Now(),
+	})
+
+	f.Store.UpdateAlerts(store.AlertsData{
+		SectionMeta: store.SectionMeta{Source: "digitransit"},
+		Alerts:      alerts.list(time.Now()),
 	})
 
+	// Stops left out of an otherwise successful fetch are reported on the
+	// section instead of only in the log
+	if len(unknown) > 0 {
+		unresolved = append(unresolved, fmt.Errorf("%w: %s", ErrUnknownStop, strings.Join(unknown, ", ")))
+	}
+	if len(unresolved) > 0 {
+		f.Store.SetError(store.SectionTransport, errors.Join(unresolved...))
+	}
+
 	log.Println("HSL: Fetch completed successfully")
 	return nil
 }
 
-// Helper to read body without consuming it (for potential debugging, though here we just read it)
-func bodyBytes(r io.Reader) []byte {
-	b, _ := io.ReadAll(r)
-	return b
+// departureWindow returns the query start time (Unix seconds, 0 for now) and
+// how many departures to request for a stop. Departures sooner than the
+// walking time are skipped by starting the query later; filtered stops are
+// over-fetched.
+func departureWindow(stop config.BusStop, now time.Time) (int64, int) {
+	var startTime int64
+	if stop.WalkingTime.Duration > 0 {
+		startTime = now.Add(stop.WalkingTime.Duration).Unix()
+	}
+	count := stop.DepartureCount()
+	if stop.HasFilters() {
+		count = min(count*overFetchFactor, maxFetchCount)
+	}
+	return startTime, count
+}
+
+// isSkipped reports whether a realtime update makes a trip pass the stop
+// without stopping. Skipped stops lose both boarding and alighting; at a
+// terminus or a drop-off only stop just boarding is NONE, also in the
+// schedule, so pickup alone says nothing.
+func isSkipped(realtime bool, state, pickup, dropoff string) bool {
+	return realtime && state != "CANCELED" && pickup == "NONE" && dropoff == "NONE"
+}
+
+// routeMode maps GTFS route modes to the names used by the dashboard
+func routeMode(mode string) string {
+	switch mode {
+	case "BUS":
+		return "bus"
+	case "TRAM":
+		return "tram"
+	case "SUBWAY":
+		return "metro"
+	case "RAIL":
+		return "train"
+	case "FERRY":
+		return "ferry"
+	}
+	return strings.ToLower(mode)
+}
+
+// resolveStop maps a configured stop id to a GTFS id, via the cache if set
+func (f *HSLFetcher) resolveStop(ctx context.Context, stopID string) (string, error) {
+	if f.Stops != nil {
+		return f.Stops.Resolve(ctx, stopID)
+	}
+	// Check if ID needs resolution (e.g. E1234-style code or plain code)
+	if strings.HasPrefix(stopID, "HSL:") {
+		return stopID, nil
+	}
+	log.Printf("Resolving stop code via geocoding API: %s", stopID)
+	id, err := f.LookupStop(ctx, stopID)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Resolved %s to GTFS id %s", stopID, id)
+	return id, nil
 }
 
 // LookupStop resolves human-friendly stop codes (e.g. E2185) into GTFS ids (HSL:xxxxx)
 // using the Digitransit Pelias geocoding API.
-func (f *HSLFetcher) LookupStop(shortCode string) (string, error) {
-	// Build geocoding request URL
-	url := fmt.Sprintf("https://api.digitransit.fi/geocoding/v1/search?text=%s&size=1&layers=stop&sources=gtfshsl", shortCode)
+func (f *HSLFetcher) LookupStop(ctx context.Context, shortCode string) (string, error) {
+	cfg := f.Config.Get()
 
-	req, err := http.NewRequest("GET", url, nil)
+	// Build geocoding request URL
+	u, err := url.Parse(cfg.GeocodingAPIUrl)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("invalid geocoding url: %w", err)
 	}
-	req.Header.Set("digitransit-subscription-key", f.Config.HSLKey)
+	u.RawQuery = url.Values{
+		"text":    {shortCode},
+		"size":    {"1"},
+		"layers":  {"stop"},
+		"sources": {"gtfshsl"},
+	}.Encode()
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	header := http.Header{}
+	header.Set("digitransit-subscription-key", cfg.HSLKey)
+	resp, err := f.HTTP.Get(ctx, u.String(), header)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("geocoding api returned status: %d", resp.StatusCode)
@@ -199,7 +367,7 @@ func (f *HSLFetcher) LookupStop(shortCode string) (string, error) {
 		} `json:"features"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
 		return "", err
 	}
 
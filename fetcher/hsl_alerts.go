@@ -0,0 +1,91 @@
+package fetcher
+
+import (
+	"rasp_info/store"
+	"slices"
+	"sort"
+	"time"
+)
+
+// alertCollector merges alerts seen on several stops and routes. The same
+// alert often applies to many of them; it is reported once with every
+// affected stop and route listed.
+type alertCollector struct {
+	byID  map[string]*store.Alert
+	order []string
+}
+
+func newAlertCollector() *alertCollector {
+	return &alertCollector{byID: make(map[string]*store.Alert)}
+}
+
+// add records an alert affecting stopName and/or route (either may be empty)
+func (c *alertCollector) add(a AlertResponse, stopName, route string) {
+	alert, ok := c.byID[a.ID]
+	if !ok {
+		alert = &store.Alert{
+			ID:          a.ID,
+			Severity:    a.AlertSeverityLevel,
+			Header:      translations(a.AlertHeaderTextTranslations, a.AlertHeaderText),
+			Description: translations(a.AlertDescriptionTextTranslations, a.AlertDescriptionText),
+			URL:         a.AlertURL,
+		}
+		if a.EffectiveStartDate > 0 {
+			alert.ValidFrom = time.Unix(a.EffectiveStartDate, 0)
+		}
+		if a.EffectiveEndDate > 0 {
+			alert.ValidTo = time.Unix(a.EffectiveEndDate, 0)
+		}
+		c.byID[a.ID] = alert
+		c.order = append(c.order, a.ID)
+	}
+	if stopName != "" && !slices.Contains(alert.Stops, stopName) {
+		alert.Stops = append(alert.Stops, stopName)
+	}
+	if route != "" && !slices.Contains(alert.Routes, route) {
+		alert.Routes = append(alert.Routes, route)
+	}
+}
+
+// list returns alerts still valid at now, most severe first
+func (c *alertCollector) list(now time.Time) []store.Alert {
+	var out []store.Alert
+	for _, id := range c.order {
+		a := c.byID[id]
+		if !a.ValidTo.IsZero() && a.ValidTo.Before(now) {
+			continue
+		}
+		out = append(out, *a)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return severityRank(out[i].Severity) > severityRank(out[j].Severity)
+	})
+	return out
+}
+
+func severityRank(s string) int {
+	switch s {
+	case "SEVERE":
+		return 3
+	case "WARNING":
+		return 2
+	case "INFO":
+		return 1
+	}
+	return 0
+}
+
+// translations maps language -> text, falling back to the untranslated text
+// under the empty language if no translations are given
+func translations(tr []TranslatedText, fallback string) map[string]string {
+	m := make(map[string]string)
+	for _, t := range tr {
+		if t.Text != "" {
+			m[t.Language] = t.Text
+		}
+	}
+	if len(m) == 0 && fallback != "" {
+		m[""] = fallback
+	}
+	return m
+}
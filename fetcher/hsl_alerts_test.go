@@ -0,0 +1,66 @@
+package fetcher
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAlertCollector(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	diversion := AlertResponse{
+		ID:                          "diversion",
+		AlertSeverityLevel:          "WARNING",
+		EffectiveEndDate:            now.Add(time.Hour).Unix(),
+		AlertHeaderTextTranslations: []TranslatedText{{Text: "Poikkeusreitti", Language: "fi"}, {Text: "Diversion", Language: "en"}},
+	}
+	closed := AlertResponse{ID: "closed", AlertSeverityLevel: "SEVERE", AlertHeaderText: "Stop closed"}
+	info := AlertResponse{ID: "info", AlertSeverityLevel: "INFO", AlertHeaderText: "New timetable"}
+	expired := AlertResponse{ID: "expired", AlertSeverityLevel: "SEVERE", EffectiveEndDate: now.Add(-time.Minute).Unix()}
+
+	c := newAlertCollector()
+	c.add(info, "Kamppi", "")
+	c.add(diversion, "Kamppi", "")
+	c.add(diversion, "", "110")
+	c.add(diversion, "Tapiola", "")
+	c.add(diversion, "", "110") // Same route again
+	c.add(diversion, "Kamppi", "114")
+	c.add(expired, "Kamppi", "")
+	c.add(closed, "Tapiola", "")
+
+	got := c.list(now)
+	var ids []string
+	for _, a := range got {
+		ids = append(ids, a.ID)
+	}
+	// Most severe first; the expired alert is dropped
+	if want := []string{"closed", "diversion", "info"}; !slices.Equal(ids, want) {
+		t.Fatalf("alerts = %v, want %v", ids, want)
+	}
+
+	d := got[1]
+	if want := []string{"Kamppi", "Tapiola"}; !slices.Equal(d.Stops, want) {
+		t.Errorf("diversion stops = %v, want %v", d.Stops, want)
+	}
+	if want := []string{"110", "114"}; !slices.Equal(d.Routes, want) {
+		t.Errorf("diversion routes = %v, want %v", d.Routes, want)
+	}
+	if d.Header["fi"] != "Poikkeusreitti" || d.Header["en"] != "Diversion" || !d.ValidTo.Equal(now.Add(time.Hour)) {
+		t.Errorf("diversion = %+v", d)
+	}
+	if got[0].Header[""] != "Stop closed" || len(got[0].Routes) != 0 {
+		t.Errorf("untranslated alert = %+v", got[0])
+	}
+
+	// Alerts of equal severity keep the order they were first seen in
+	c.add(AlertResponse{ID: "info2", AlertSeverityLevel: "INFO"}, "Kamppi", "")
+	got = c.list(now)
+	if len(got) != 4 || got[2].ID != "info" || got[3].ID != "info2" {
+		t.Errorf("alerts = %+v, want info before info2", got)
+	}
+
+	// Once the diversion ends it is dropped too
+	if got := c.list(now.Add(2 * time.Hour)); len(got) != 3 {
+		t.Errorf("got %d alerts after the diversion ended, want 3", len(got))
+	}
+}
@@ -0,0 +1,298 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"rasp_info/config"
+	"rasp_info/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHSLFetcher(t *testing.T) {
+	twoStops := []config.BusStop{
+		{ID: "HSL:2222222", Name: "Koti"},
+		{ID: "HSL:3333333", Name: "Gone"},
+	}
+
+	tests := []struct {
+		name    string
+		stops   []config.BusStop
+		status  int
+		body    []byte
+		wantErr error
+		check   func(t *testing.T, d store.Data)
+	}{
+		{
+			name:   "departures and alerts",
+			stops:  twoStops,
+			status: http.StatusOK,
+			body:   readTestdata(t, "hsl_stops.json"),
+			check: func(t *testing.T, d store.Data) {
+				if len(d.Transport.Stops) != 1 {
+					t.Fatalf("got %d stops, want 1 (unknown stop skipped)", len(d.Transport.Stops))
+				}
+				if !strings.Contains(d.Transport.LastError, "HSL:3333333") {
+					t.Errorf("last_error = %q, want the unknown stop", d.Transport.LastError)
+				}
+				stop := d.Transport.Stops[0]
+				if stop.StopName != "Koti" || stop.StopCode != "E2185" {
+					t.Errorf("stop = %q (%q), want Koti (E2185)", stop.StopName, stop.StopCode)
+				}
+				if len(stop.Departures) != 3 {
+					t.Fatalf("got %d departures, want 3", len(stop.Departures))
+				}
+				first := stop.Departures[0]
+				if first.RouteNumber != "110" || first.DelaySeconds != 120 || first.Platform != "12" || !first.Realtime {
+					t.Errorf("first departure = %+v", first)
+				}
+				if got := first.Time.Sub(first.ScheduledTime); got != 2*time.Minute {
+					t.Errorf("realtime - scheduled = %s, want 2m", got)
+				}
+				if !stop.Departures[1].Cancelled {
+					t.Errorf("second departure should be cancelled")
+				}
+				if stop.Departures[2].Mode != "metro" {
+					t.Errorf("mode = %q, want metro", stop.Departures[2].Mode)
+				}
+				if len(d.Alerts.Alerts) != 1 {
+					t.Fatalf("got %d alerts, want 1", len(d.Alerts.Alerts))
+				}
+				alert := d.Alerts.Alerts[0]
+				if alert.Header["en"] != "Diversion" || len(alert.Routes) != 1 || alert.Routes[0] != "110" {
+					t.Errorf("alert = %+v", alert)
+				}
+				if alert.Description[""] == "" {
+					t.Errorf("untranslated description should fall back to the plain text")
+				}
+			},
+		},
+		{
+			name:   "route filter",
+			stops:  []config.BusStop{{ID: "HSL:2222222", Name: "Koti", Routes: []string{"114"}}},
+			status: http.StatusOK,
+			body:   readTestdata(t, "hsl_stops.json"),
+			check: func(t *testing.T, d store.Data) {
+				if d.Transport.LastError != "" {
+					t.Errorf("last_error = %q, want none", d.Transport.LastError)
+				}
+				deps := d.Transport.Stops[0].Departures
+				if len(deps) != 1 || deps[0].RouteNumber != "114" {
+					t.Errorf("departures = %+v, want only route 114", deps)
+				}
+				if len(d.Alerts.Alerts) != 0 {
+					t.Errorf("alerts for filtered-out routes should be dropped, got %d", len(d.Alerts.Alerts))
+				}
+			},
+		},
+		{
+			name:    "all stops unknown",
+			stops:   []config.BusStop{{ID: "HSL:3333333"}},
+			status:  http.StatusOK,
+			body:    []byte(`{"data":{"stop0":null}}`),
+			wantErr: ErrUnknownStop,
+		},
+		{
+			name:    "bad key",
+			stops:   twoStops,
+			status:  http.StatusUnauthorized,
+			body:    []byte(`Access denied`),
+			wantErr: ErrAuth,
+		},
+		{
+			name:    "rate limited",
+			stops:   twoStops,
+			status:  http.StatusTooManyRequests,
+			wantErr: ErrRateLimited,
+		},
+		{
+			name:    "query error",
+			stops:   twoStops,
+			status:  http.StatusOK,
+			body:    []byte(`{"data":null,"errors":[{"message":"Validation error of type FieldUndefined"}]}`),
+			wantErr: ErrGraphQL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.status, tt.body)
+			st := store.New()
+			cfg := testConfig(func(c *config.Config) {
+				c.HSLAPIUrl = srv.URL
+				c.BusStops = tt.stops
+			})
+			f := &HSLFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+			err := f.Fetch(context.Background())
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			req, body := srv.last()
+			if got := req.Header.Get("digitransit-subscription-key"); got != "test-key" {
+				t.Errorf("subscription key = %q", got)
+			}
+			var sent struct {
+				Variables map[string]any `json:"variables"`
+			}
+			if err := json.Unmarshal(body, &sent); err != nil {
+				t.Fatal(err)
+			}
+			if sent.Variables["v0"] != tt.stops[0].ID {
+				t.Errorf("stop id should be sent as a variable, got %v", sent.Variables)
+			}
+			tt.check(t, st.Get())
+		})
+	}
+}
+
+func TestHSLFetcherStopsMissing(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, readTestdata(t, "hsl_stops.json"))
+	geocoding := newTestServer(t, http.StatusOK, []byte(`{"features":[]}`))
+	st := store.New()
+	st.UpdateTransport(store.TransportData{Stops: []store.StopData{{StopName: "Old"}}})
+	st.UpdateAlerts(store.AlertsData{Alerts: []store.Alert{{ID: "old"}}})
+	stops := []config.BusStop{{ID: "HSL:2222222", Name: "Koti"}, {ID: "E9999", Name: "Typo"}}
+	fetch := func(stops []config.BusStop) error {
+		cfg := testConfig(func(c *config.Config) {
+			c.HSLAPIUrl = srv.URL
+			c.GeocodingAPIUrl = geocoding.URL
+			c.BusStops = stops
+		})
+		f := &HSLFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+		return f.Fetch(context.Background())
+	}
+
+	// A stop that cannot be resolved is reported on the section
+	if err := fetch(stops); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if d := st.Get().Transport; len(d.Stops) != 1 || !strings.Contains(d.LastError, "E9999") {
+		t.Errorf("transport = %d stops, last_error %q; want 1 stop and E9999 reported", len(d.Stops), d.LastError)
+	}
+
+	// No stop resolving fails the fetch
+	if err := fetch(stops[1:]); err == nil {
+		t.Error("Fetch() with no resolvable stops succeeded")
+	}
+
+	// No stops configured clears the sections without asking the API
+	req, _ := srv.last()
+	if err := fetch(nil); err != nil {
+		t.Fatalf("Fetch() without stops error = %v", err)
+	}
+	if last, _ := srv.last(); last != req {
+		t.Error("Fetch() without stops queried the API")
+	}
+	d := st.Get()
+	if len(d.Transport.Stops) != 0 || len(d.Alerts.Alerts) != 0 || d.Transport.LastError != "" || d.Transport.FetchedAt.IsZero() {
+		t.Errorf("transport = %+v, alerts = %+v; want both empty and fresh", d.Transport, d.Alerts)
+	}
+}
+
+func TestIsSkipped(t *testing.T) {
+	tests := []struct {
+		name     string
+		realtime bool
+		state    string
+		pickup   string
+		dropoff  string
+		want     bool
+	}{
+		{name: "regular stop", realtime: true, state: "UPDATED", pickup: "SCHEDULED", dropoff: "SCHEDULED"},
+		{name: "skipped by update", realtime: true, state: "UPDATED", pickup: "NONE", dropoff: "NONE", want: true},
+		{name: "terminus", realtime: true, state: "UPDATED", pickup: "NONE", dropoff: "SCHEDULED"},
+		{name: "drop-off only", realtime: true, state: "UPDATED", pickup: "NONE", dropoff: "COORDINATE_WITH_DRIVER"},
+		{name: "cancelled trip", realtime: true, state: "CANCELED", pickup: "NONE", dropoff: "NONE"},
+		{name: "no realtime", state: "SCHEDULED", pickup: "NONE", dropoff: "NONE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSkipped(tt.realtime, tt.state, tt.pickup, tt.dropoff); got != tt.want {
+				t.Errorf("isSkipped = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepartureWindow(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		stop      config.BusStop
+		wantStart int64
+		wantCount int
+	}{
+		{name: "defaults", wantCount: config.DefaultDepartures},
+		{name: "departure count", stop: config.BusStop{Departures: 6}, wantCount: 6},
+		{name: "walking time starts later", stop: config.BusStop{WalkingTime: config.Duration{Duration: 4 * time.Minute}}, wantStart: now.Add(4 * time.Minute).Unix(), wantCount: config.DefaultDepartures},
+		{name: "route filter over-fetches", stop: config.BusStop{Routes: []string{"110"}}, wantCount: config.DefaultDepartures * overFetchFactor},
+		{name: "excluded routes over-fetch", stop: config.BusStop{ExcludeRoutes: []string{"N12"}, Departures: 6}, wantCount: 6 * overFetchFactor},
+		{name: "headsign filter over-fetches", stop: config.BusStop{Headsigns: []string{"Kamppi"}}, wantCount: config.DefaultDepartures * overFetchFactor},
+		{name: "excluded headsigns over-fetch", stop: config.BusStop{ExcludeHeadsigns: []string{"Kamppi"}}, wantCount: config.DefaultDepartures * overFetchFactor},
+		{name: "over-fetch is capped", stop: config.BusStop{Routes: []string{"110"}, Departures: config.MaxDepartures}, wantCount: maxFetchCount},
+		{
+			name:      "walking time and filter",
+			stop:      config.BusStop{Headsigns: []string{"Kamppi"}, WalkingTime: config.Duration{Duration: 10 * time.Minute}},
+			wantStart: now.Add(10 * time.Minute).Unix(),
+			wantCount: config.DefaultDepartures * overFetchFactor,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, count := departureWindow(tt.stop, now)
+			if start != tt.wantStart || count != tt.wantCount {
+				t.Errorf("departureWindow() = %d, %d; want %d, %d", start, count, tt.wantStart, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestLookupStop(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "found",
+			body: `{"features":[{"properties":{"gid":"gtfshsl:stop:GTFS:HSL:2222222#E2185"}}]}`,
+			want: "HSL:2222222",
+		},
+		{name: "no features", body: `{"features":[]}`, wantErr: true},
+		{name: "odd gid", body: `{"features":[{"properties":{"gid":"stop:1"}}]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, http.StatusOK, []byte(tt.body))
+			cfg := testConfig(func(c *config.Config) { c.GeocodingAPIUrl = srv.URL + "/geocoding/v1/search" })
+			f := &HSLFetcher{Config: cfg, HTTP: NewHTTPClient(cfg)}
+
+			got, err := f.LookupStop(context.Background(), "E2185")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			req, _ := srv.last()
+			if req.URL.Path != "/geocoding/v1/search" || req.URL.Query().Get("text") != "E2185" {
+				t.Errorf("request = %s", req.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"rasp_info/config"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// UserAgent identifies the dashboard to the APIs it calls
+const UserAgent = "raspberry-infoboard/1.0 (+https://github.com/otsolappalainen/raspberry_infoboard)"
+
+const (
+	defaultTimeout = 10 * time.Second
+	defaultRetries = 2
+	maxRetryWait   = 30 * time.Second // Longer Retry-After values are not waited for
+	maxCached      = 32               // Responses kept for conditional requests
+)
+
+// Response is a fully read HTTP response
+type Response struct {
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+	NotModified bool // Server answered 304; Body is the cached copy
+}
+
+// HTTPClient is the HTTP layer shared by all fetchers. It applies the
+// configured timeout to every attempt, retries 5xx and 429 responses
+// (honoring Retry-After), makes conditional GET requests with
+// ETag/Last-Modified and accepts gzip. Retries that could not finish before
+// the request context's deadline are skipped.
+type HTTPClient struct {
+	Config *config.Manager // Optional; without it defaults are used
+	Client *http.Client    // Optional; defaults to a client without compression
+
+	mu    sync.Mutex
+	cache map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	etag         string
+	lastModified string
+	header       http.Header
+	body         []byte
+	storedAt     time.Time
+}
+
+// NewHTTPClient creates a client reading its timeout and retry count from cfg
+func NewHTTPClient(cfg *config.Manager) *HTTPClient {
+	return &HTTPClient{Config: cfg}
+}
+
+// defaultHTTP is used by fetchers created without an HTTPClient
+var defaultHTTP = &HTTPClient{}
+
+// orDefault lets fetchers call methods on a nil *HTTPClient
+func (c *HTTPClient) orDefault() *HTTPClient {
+	if c == nil {
+		return defaultHTTP
+	}
+	return c
+}
+
+// transport is the underlying client. Compression is disabled so that gzip
+// is requested and decoded here, also when a response is cached.
+var transport = &http.Client{Transport: &http.Transport{
+	Proxy:              http.ProxyFromEnvironment,
+	DisableCompression: true,
+	MaxIdleConns:       10,
+	IdleConnTimeout:    90 * time.Second,
+}}
+
+func (c *HTTPClient) settings() (time.Duration, int) {
+	timeout, retries := defaultTimeout, defaultRetries
+	if c.Config != nil {
+		cfg := c.Config.Get()
+		if cfg.HTTPTimeout.Duration > 0 {
+			timeout = cfg.HTTPTimeout.Duration
+		}
+		retries = cfg.HTTPRetries
+	}
+	return timeout, retries
+}
+
+// Get fetches url, adding the given headers
+func (c *HTTPClient) Get(ctx context.Context, url string, header http.Header) (*Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return c.Do(req)
+}
+
+// Do sends req, retrying transient failures. Non-2xx responses are returned
+// as is once retries are exhausted; only transport errors are returned as
+// errors. The request body must be replayable (GetBody set), as it is for
+// bodies created from bytes or strings.
+func (c *HTTPClient) Do(req *http.Request) (*Response, error) {
+	c = c.orDefault()
+	timeout, retries := c.settings()
+
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept-Encoding", "gzip")
+	key := req.Method + " " + req.URL.String()
+	cached, haveCached := c.lookup(req.Method, key)
+	if haveCached {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(req, timeout)
+		if err != nil && req.Context().Err() != nil {
+			return nil, err // Cancelled by the caller, don't retry
+		}
+
+		var wait time.Duration
+		switch {
+		case err != nil:
+			wait = backoff(attempt)
+		case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+			wait = retryAfter(resp.Header, time.Now())
+			if wait == 0 {
+				wait = backoff(attempt)
+			}
+		case resp.StatusCode == http.StatusNotModified && haveCached:
+			return &Response{StatusCode: http.StatusOK, Header: cached.header, Body: cached.body, NotModified: true}, nil
+		default:
+			if resp.StatusCode == http.StatusOK {
+				c.store(req.Method, key, resp)
+			}
+			return resp, nil
+		}
+
+		if attempt >= retries || wait > maxRetryWait || !beforeDeadline(req.Context(), wait+timeout) {
+			if err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		if err != nil {
+			log.Printf("HTTP: %s %s failed, retrying in %s: %v", req.Method, req.URL.Host, wait, err)
+		} else {
+			log.Printf("HTTP: %s %s returned %d, retrying in %s", req.Method, req.URL.Host, resp.StatusCode, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt sends one copy of req and reads the whole body within timeout
+func (c *HTTPClient) attempt(req *http.Request, timeout time.Duration) (*Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	r := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, body, err := c.open(r)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}, nil
+}
+
+// Stream fetches url like Get, but hands the body to read as it arrives
+// instead of buffering it. Failures are retried as in Do until a response is
+// passed to read; streamed responses are not cached. read is called with the
+// final status, whatever it is, and must finish within the timeout.
+func (c *HTTPClient) Stream(ctx context.Context, url string, header http.Header, read func(status int, body io.Reader) error) error {
+	c = c.orDefault()
+	timeout, retries := c.settings()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	for attempt := 0; ; attempt++ {
+		wait, err := c.streamAttempt(req, timeout, attempt, attempt >= retries, read)
+		if wait == 0 {
+			return err
+		}
+		log.Printf("HTTP: GET %s failed, retrying in %s: %v", req.URL.Host, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// streamAttempt sends one copy of req for Stream. It returns how long to
+// wait before retrying, or zero once the response was handed to read or the
+// request failed for good.
+func (c *HTTPClient) streamAttempt(req *http.Request, timeout time.Duration, attempt int, final bool, read func(int, io.Reader) error) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	resp, body, err := c.open(req.Clone(ctx))
+	if err != nil {
+		wait := backoff(attempt)
+		if final || req.Context().Err() != nil || !beforeDeadline(req.Context(), wait+timeout) {
+			return 0, err
+		}
+		return wait, err
+	}
+	defer body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		wait := retryAfter(resp.Header, time.Now())
+		if wait == 0 {
+			wait = backoff(attempt)
+		}
+		if !final && wait <= maxRetryWait && beforeDeadline(req.Context(), wait+timeout) {
+			io.Copy(io.Discard, body) // Lets the connection be reused
+			return wait, fmt.Errorf("status %d", resp.StatusCode)
+		}
+	}
+	return 0, read(resp.StatusCode, body)
+}
+
+// open sends r and returns the response with its body decompressed. The
+// caller closes the returned body.
+func (c *HTTPClient) open(r *http.Request) (*http.Response, io.ReadCloser, error) {
+	client := c.Client
+	if client == nil {
+		client = transport
+	}
+	resp, err := client.Do(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, resp.Body, nil
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to decompress response: %w", err)
+	}
+	return resp, gzipBody{gz, resp.Body}, nil
+}
+
+// gzipBody closes both the decompressor and the underlying body
+type gzipBody struct {
+	*gzip.Reader
+	raw io.Closer
+}
+
+func (b gzipBody) Close() error {
+	b.Reader.Close()
+	return b.raw.Close()
+}
+
+func (c *HTTPClient) lookup(method, key string) (cachedResponse, bool) {
+	if method != "GET" {
+		return cachedResponse{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.cache[key]
+	return e, ok
+}
+
+// store remembers a GET response that carries validators
+func (c *HTTPClient) store(method, key string, resp *Response) {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if method != "GET" || etag == "" && lastModified == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cache == nil {
+		c.cache = make(map[string]cachedResponse)
+	}
+	if _, ok := c.cache[key]; !ok && len(c.cache) >= maxCached {
+		var oldest string
+		for k, e := range c.cache {
+			if oldest == "" || e.storedAt.Before(c.cache[oldest].storedAt) {
+				oldest = k
+			}
+		}
+		delete(c.cache, oldest)
+	}
+	c.cache[key] = cachedResponse{
+		etag:         etag,
+		lastModified: lastModified,
+		header:       resp.Header,
+		body:         bytes.Clone(resp.Body),
+		storedAt:     time.Now(),
+	}
+}
+
+// beforeDeadline reports whether a retry that starts after wait and runs for
+// its full timeout d would still end before ctx's deadline. A retry that is
+// bound to be cut short only delays reporting the last failure.
+func beforeDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) >= d
+}
+
+// backoff is the wait before retry attempt+1 when the server gave no hint
+func backoff(attempt int) time.Duration {
+	return time.Second << attempt
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
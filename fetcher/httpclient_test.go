@@ -0,0 +1,173 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"rasp_info/config"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTTPClientRetryDeadline(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client := NewHTTPClient(testConfig(func(c *config.Config) {
+		c.HTTPTimeout = config.Duration{Duration: 200 * time.Millisecond}
+		c.HTTPRetries = 1
+	}))
+	calls := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{name: "Do", call: func(ctx context.Context) error {
+			_, err := client.Get(ctx, srv.URL, nil)
+			return err
+		}},
+		{name: "Stream", call: func(ctx context.Context) error {
+			return client.Stream(ctx, srv.URL, nil, func(int, io.Reader) error { return nil })
+		}},
+	}
+	tests := []struct {
+		name         string
+		deadline     time.Duration
+		wantRequests int32
+	}{
+		{name: "retry fits", deadline: 5 * time.Second, wantRequests: 2},
+		{name: "retry would pass deadline", deadline: time.Second, wantRequests: 1},
+	}
+	for _, c := range calls {
+		for _, tt := range tests {
+			t.Run(c.name+"/"+tt.name, func(t *testing.T) {
+				requests.Store(0)
+				ctx, cancel := context.WithTimeout(context.Background(), tt.deadline)
+				defer cancel()
+				if err := c.call(ctx); err != nil {
+					t.Fatalf("error = %v, want the final response", err)
+				}
+				if got := requests.Load(); got != tt.wantRequests {
+					t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+				}
+			})
+		}
+	}
+}
+
+func TestHTTPClientConditionalGet(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator string // Response header carrying the validator
+		value     string
+		request   string // Request header it must come back in
+	}{
+		{name: "etag", validator: "ETag", value: `"v1"`, request: "If-None-Match"},
+		{name: "last modified", validator: "Last-Modified", value: "Fri, 16 Oct 2026 08:00:00 GMT", request: "If-Modified-Since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if r.Header.Get(tt.request) == tt.value {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set(tt.validator, tt.value)
+				w.Write([]byte("prices"))
+			}))
+			defer srv.Close()
+			client := NewHTTPClient(testConfig(nil))
+
+			first, err := client.Get(context.Background(), srv.URL, nil)
+			if err != nil || first.NotModified || string(first.Body) != "prices" {
+				t.Fatalf("first response = %+v, %v", first, err)
+			}
+			second, err := client.Get(context.Background(), srv.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !second.NotModified || second.StatusCode != http.StatusOK || string(second.Body) != "prices" {
+				t.Errorf("second response = %d %q (not modified %v), want the cached body", second.StatusCode, second.Body, second.NotModified)
+			}
+			if requests.Load() != 2 {
+				t.Errorf("requests = %d, want 2", requests.Load())
+			}
+		})
+	}
+}
+
+func TestHTTPClientHeadersAndGzip(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"ok":true}`))
+	gz.Close()
+
+	var userAgent, acceptEncoding string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, acceptEncoding = r.UserAgent(), r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed.Bytes())
+	}))
+	defer srv.Close()
+	client := NewHTTPClient(testConfig(nil))
+
+	resp, err := client.Get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != `{"ok":true}` {
+		t.Errorf("body = %q, want it decompressed", resp.Body)
+	}
+	if !strings.HasPrefix(userAgent, "raspberry-infoboard/") || acceptEncoding != "gzip" {
+		t.Errorf("User-Agent %q, Accept-Encoding %q", userAgent, acceptEncoding)
+	}
+
+	var streamed string
+	err = client.Stream(context.Background(), srv.URL, nil, func(status int, body io.Reader) error {
+		data, err := io.ReadAll(body)
+		streamed = string(data)
+		return err
+	})
+	if err != nil || streamed != `{"ok":true}` {
+		t.Errorf("streamed body = %q, %v", streamed, err)
+	}
+}
+
+func TestHTTPClientRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	var retryAt time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			retryAt = time.Now().Add(time.Second)
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if time.Now().Before(retryAt) {
+			t.Errorf("retried %s before Retry-After", retryAt.Sub(time.Now()))
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	client := NewHTTPClient(testConfig(func(c *config.Config) { c.HTTPRetries = 1 }))
+
+	resp, err := client.Get(context.Background(), srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || requests.Load() != 2 {
+		t.Errorf("status %d after %d requests, want 200 after 2", resp.StatusCode, requests.Load())
+	}
+}
@@ -1,25 +1,39 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
 	"rasp_info/store"
 	"time"
 )
 
 // LoggingFetcher wraps a Fetcher and logs its execution
 type LoggingFetcher struct {
-	Fetcher Fetcher
-	Store   *store.Store
-	Name    string
+	Fetcher  Fetcher
+	Store    *store.Store
+	Name     string
+	Sections []string // Store sections to record errors against (optional)
 }
 
-func (l *LoggingFetcher) Fetch() error {
+func (l *LoggingFetcher) Fetch(ctx context.Context) error {
 	start := time.Now()
-	err := l.Fetcher.Fetch()
+	err := l.Fetcher.Fetch(ctx)
 	duration := time.Since(start)
 
 	status := "success"
 	errorMsg := ""
-	if err != nil {
+	// A fetch cancelled by shutdown says nothing about the source, so it is
+	// not recorded as a section error. Running into the fetch deadline does.
+	cancelled := err != nil && errors.Is(ctx.Err(), context.Canceled)
+
+	switch {
+	case cancelled:
+		status = "cancelled"
+		errorMsg = err.Error()
+	case errors.Is(err, ErrNotReady):
+		status = "waiting"
+		errorMsg = err.Error()
+	case err != nil:
 		status = "error"
 		errorMsg = err.Error()
 	}
@@ -33,5 +47,19 @@ func (l *LoggingFetcher) Fetch() error {
 		Error:     errorMsg,
 	})
 
+	if err != nil && !cancelled && !errors.Is(err, ErrNotReady) {
+		for _, section := range l.Sections {
+			l.Store.SetError(section, err)
+		}
+	}
+
 	return err
 }
+
+// SetNextRun passes the next scheduled run on to the store, so the sections
+// expire relative to it
+func (l *LoggingFetcher) SetNextRun(t time.Time) {
+	for _, section := range l.Sections {
+		l.Store.SetNextRun(section, t)
+	}
+}
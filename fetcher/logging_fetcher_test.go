@@ -0,0 +1,67 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+// fetchFunc adapts a function to Fetcher
+type fetchFunc func(ctx context.Context) error
+
+func (f fetchFunc) Fetch(ctx context.Context) error { return f(ctx) }
+
+func TestLoggingFetcher(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancel     bool // Cancel the context before fetching, like Stop does
+		timeout    bool // Let the context run past its deadline
+		err        error
+		wantStatus string
+		wantError  string // Section error afterwards
+	}{
+		{name: "success", wantStatus: "success"},
+		{name: "failure", err: errors.New("fmi down"), wantStatus: "error", wantError: "fmi down"},
+		{name: "not ready", err: fmt.Errorf("%w: no coordinates", ErrNotReady), wantStatus: "waiting"},
+		{name: "cancelled on shutdown", cancel: true, wantStatus: "cancelled"},
+		{name: "deadline exceeded", timeout: true, wantStatus: "error", wantError: context.DeadlineExceeded.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := store.New()
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.timeout {
+				ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond)
+			}
+			defer cancel()
+			if tt.cancel {
+				cancel()
+			}
+			l := &LoggingFetcher{
+				Fetcher: fetchFunc(func(ctx context.Context) error {
+					if tt.cancel || tt.timeout {
+						<-ctx.Done()
+						return ctx.Err()
+					}
+					return tt.err
+				}),
+				Store:    st,
+				Name:     "FMI",
+				Sections: []string{store.SectionWeather},
+			}
+
+			l.Fetch(ctx)
+			logs := st.Get().APICalls
+			if len(logs) != 1 || logs[0].Status != tt.wantStatus {
+				t.Fatalf("logs = %+v, want one with status %q", logs, tt.wantStatus)
+			}
+			if got := st.Get().Weather.LastError; got != tt.wantError {
+				t.Errorf("section error = %q, want %q", got, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"rasp_info/config"
+	"rasp_info/store"
+	"time"
+)
+
+// planQueryFields is the body of one aliased plan field. The placeholders
+// are variable references for from, to, date, time, arriveBy and count.
+const planQueryFields = `
+  plan(
+    from: %s
+    to: %s
+    date: %s
+    time: %s
+    arriveBy: %s
+    numItineraries: %s
+  ) {
+    itineraries {
+      startTime
+      endTime
+      duration
+      walkDistance
+      legs {
+        mode
+        startTime
+        endTime
+        realTime
+        distance
+        transitLeg
+        from {
+          name
+        }
+        to {
+          name
+        }
+        route {
+          shortName
+        }
+        trip {
+          tripHeadsign
+        }
+      }
+    }
+  }
+`
+
+// PlannerFetcher plans the saved trips from config with the Digitransit
+// routing API, using the same endpoint and key as HSLFetcher
+type PlannerFetcher struct {
+	Config *config.Manager
+	Store  *store.Store
+	HTTP   *HTTPClient // Shared HTTP layer; nil uses defaults
+}
+
+type PlanResponse struct {
+	Itineraries []struct {
+		StartTime    int64   `json:"startTime"` // Unix milliseconds
+		EndTime      int64   `json:"endTime"`
+		Duration     int     `json:"duration"` // Seconds
+		WalkDistance float64 `json:"walkDistance"`
+		Legs         []struct {
+			Mode       string  `json:"mode"`
+			StartTime  int64   `json:"startTime"`
+			EndTime    int64   `json:"endTime"`
+			RealTime   bool    `json:"realTime"`
+			Distance   float64 `json:"distance"`
+			TransitLeg bool    `json:"transitLeg"`
+			From       struct {
+				Name string `json:"name"`
+			} `json:"from"`
+			To struct {
+				Name string `json:"name"`
+			} `json:"to"`
+			Route *struct {
+				ShortName string `json:"shortName"`
+			} `json:"route"`
+			Trip *struct {
+				TripHeadsign string `json:"tripHeadsign"`
+			} `json:"trip"`
+		} `json:"legs"`
+	} `json:"itineraries"`
+}
+
+func (f *PlannerFetcher) Fetch(ctx context.Context) error {
+	cfg := f.Config.Get()
+	if len(cfg.Trips) == 0 {
+		f.Store.UpdateJourneys(store.JourneysData{SectionMeta: store.SectionMeta{Source: "digitransit"}})
+		return nil
+	}
+
+	now := time.Now()
+	q := NewQueryBuilder()
+	arrivals := make([]time.Time, len(cfg.Trips))
+	for i, trip := range cfg.Trips {
+		when := now
+		arrival, arriveBy := trip.ArrivalAfter(now)
+		if arriveBy {
+			when = arrival
+			arrivals[i] = arrival
+		}
+		q.Field(fmt.Sprintf("trip%d", i), fmt.Sprintf(planQueryFields,
+			q.Var("InputCoordinates!", coordinates(trip.From)),
+			q.Var("InputCoordinates!", coordinates(trip.To)),
+			q.Var("String", when.Format("2006-01-02")),
+			q.Var("String", when.Format("15:04:05")),
+			q.Var("Boolean", arriveBy),
+			q.Var("Int", trip.ItineraryCount())))
+	}
+	query, vars := q.Build()
+
+	var result map[string]*PlanResponse
+	if err := newDigitransitClient(cfg, f.HTTP).Do(ctx, query, vars, &result); err != nil {
+		if !IsPartial(err) {
+			return fmt.Errorf("failed to fetch journey plans: %w", err)
+		}
+		log.Printf("Planner: Partial response: %v", err)
+	}
+
+	var plans []store.TripPlan
+	for i, trip := range cfg.Trips {
+		plan := result[fmt.Sprintf("trip%d", i)]
+		if plan == nil {
+			log.Printf("Planner: No plan returned for trip %s", trip.Name)
+			continue
+		}
+		plans = append(plans, store.TripPlan{
+			Name:        trip.Name,
+			From:        placeLabel(trip.From),
+			To:          placeLabel(trip.To),
+			ArriveBy:    arrivals[i],
+			Itineraries: convertItineraries(*plan),
+		})
+	}
+
+	f.Store.UpdateJourneys(store.JourneysData{
+		SectionMeta: store.SectionMeta{Source: "digitransit", FetchedAt: now},
+		Trips:       plans,
+	})
+	return nil
+}
+
+// coordinates is the InputCoordinates value for a place
+func coordinates(p config.Place) map[string]float64 {
+	return map[string]float64{"lat": p.Lat, "lon": p.Lon}
+}
+
+func convertItineraries(plan PlanResponse) []store.Itinerary {
+	var out []store.Itinerary
+	for _, it := range plan.Itineraries {
+		itinerary := store.Itinerary{
+			Start:        time.UnixMilli(it.StartTime),
+			End:          time.UnixMilli(it.EndTime),
+			Duration:     it.Duration,
+			WalkDistance: it.WalkDistance,
+		}
+		transitLegs := 0
+		for _, l := range it.Legs {
+			leg := store.Leg{
+				Mode:     routeMode(l.Mode),
+				From:     l.From.Name,
+				To:       l.To.Name,
+				Start:    time.UnixMilli(l.StartTime),
+				End:      time.UnixMilli(l.EndTime),
+				Realtime: l.RealTime,
+				Distance: l.Distance,
+			}
+			if l.Route != nil {
+				leg.Route = l.Route.ShortName
+			}
+			if l.Trip != nil {
+				leg.Headsign = l.Trip.TripHeadsign
+			}
+			if l.TransitLeg {
+				transitLegs++
+			}
+			itinerary.Legs = append(itinerary.Legs, leg)
+		}
+		if transitLegs > 1 {
+			itinerary.Transfers = transitLegs - 1
+		}
+		out = append(out, itinerary)
+	}
+	return out
+}
+
+// placeLabel names a place, falling back to its coordinates
+func placeLabel(p config.Place) string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%.4f,%.4f", p.Lat, p.Lon)
+}
@@ -0,0 +1,132 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"rasp_info/config"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+func TestPlannerFetcher(t *testing.T) {
+	home := config.Place{Name: "Koti", Lat: 60.1699, Lon: 24.9384}
+	work := config.Place{Lat: 60.2055, Lon: 24.6559}
+	trips := []config.Trip{
+		{Name: "To work", From: home, To: work, ArriveBy: "09:00", Itineraries: 2},
+		{Name: "Home", From: work, To: home},
+	}
+
+	srv := newTestServer(t, http.StatusOK, readTestdata(t, "planner.json"))
+	st := store.New()
+	cfg := testConfig(func(c *config.Config) {
+		c.HSLAPIUrl = srv.URL
+		c.Trips = trips
+	})
+	f := &PlannerFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+	now := time.Now()
+	if err := f.Fetch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Six variables per trip: from, to, date, time, arriveBy, count
+	_, body := srv.last()
+	var sent struct {
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &sent); err != nil {
+		t.Fatal(err)
+	}
+	vars := sent.Variables
+
+	t.Run("arrive by variables", func(t *testing.T) {
+		arrival, _ := trips[0].ArrivalAfter(now)
+		if vars["v4"] != true || vars["v2"] != arrival.Format("2006-01-02") || vars["v3"] != "09:00:00" {
+			t.Errorf("date %v, time %v, arriveBy %v; want %s 09:00:00 arriving", vars["v2"], vars["v3"], vars["v4"], arrival.Format("2006-01-02"))
+		}
+		if vars["v5"] != float64(2) {
+			t.Errorf("numItineraries = %v, want 2", vars["v5"])
+		}
+	})
+
+	t.Run("next departures variables", func(t *testing.T) {
+		when, err := time.ParseInLocation("2006-01-02 15:04:05", vars["v8"].(string)+" "+vars["v9"].(string), time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if vars["v10"] != false || when.Sub(now).Abs() > time.Minute {
+			t.Errorf("departing %s (arriveBy %v), want now", when, vars["v10"])
+		}
+		if vars["v11"] != float64(config.DefaultItineraries) {
+			t.Errorf("numItineraries = %v, want %d", vars["v11"], config.DefaultItineraries)
+		}
+	})
+
+	t.Run("plans", func(t *testing.T) {
+		plans := st.Get().Journeys.Trips
+		if len(plans) != 1 {
+			t.Fatalf("got %d plans, want 1 (null plan skipped)", len(plans))
+		}
+		plan := plans[0]
+		if plan.Name != "To work" || plan.From != "Koti" || plan.To != "60.2055,24.6559" {
+			t.Errorf("plan = %q from %q to %q", plan.Name, plan.From, plan.To)
+		}
+		if plan.ArriveBy.Format("15:04") != "09:00" {
+			t.Errorf("arrive by = %s, want 09:00", plan.ArriveBy)
+		}
+		if len(plan.Itineraries) != 2 {
+			t.Fatalf("got %d itineraries, want 2", len(plan.Itineraries))
+		}
+		direct := plan.Itineraries[0]
+		if direct.Duration != 1800 || direct.WalkDistance != 420.5 || len(direct.Legs) != 3 {
+			t.Errorf("itinerary = %+v", direct)
+		}
+		bus := direct.Legs[1]
+		if bus.Mode != "bus" || bus.Route != "110" || bus.Headsign != "Kamppi" || !bus.Realtime {
+			t.Errorf("bus leg = %+v", bus)
+		}
+		if walk := direct.Legs[0]; walk.Mode != "walk" || walk.Route != "" || walk.Headsign != "" {
+			t.Errorf("walk leg = %+v", walk)
+		}
+	})
+}
+
+func TestConvertItinerariesTransfers(t *testing.T) {
+	leg := func(mode string, transit bool) string {
+		b, _ := json.Marshal(map[string]any{"mode": mode, "transitLeg": transit})
+		return string(b)
+	}
+	tests := []struct {
+		name string
+		legs []string
+		want int
+	}{
+		{name: "walk only", legs: []string{leg("WALK", false)}, want: 0},
+		{name: "one vehicle", legs: []string{leg("WALK", false), leg("BUS", true), leg("WALK", false)}, want: 0},
+		{name: "walking between vehicles", legs: []string{leg("BUS", true), leg("WALK", false), leg("SUBWAY", true)}, want: 1},
+		{name: "three vehicles", legs: []string{leg("BUS", true), leg("SUBWAY", true), leg("WALK", false), leg("TRAM", true)}, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var plan PlanResponse
+			data := `{"itineraries":[{"legs":[`
+			for i, l := range tt.legs {
+				if i > 0 {
+					data += ","
+				}
+				data += l
+			}
+			data += `]}]}`
+			if err := json.Unmarshal([]byte(data), &plan); err != nil {
+				t.Fatal(err)
+			}
+			got := convertItineraries(plan)
+			if len(got) != 1 || got[0].Transfers != tt.want {
+				t.Errorf("transfers = %+v, want %d", got, tt.want)
+			}
+		})
+	}
+}
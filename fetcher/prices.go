@@ -0,0 +1,146 @@
+package fetcher
+
+import (
+	"math"
+	"rasp_info/config"
+	"rasp_info/store"
+	"sort"
+	"time"
+)
+
+// Percentile bands reported in store.PriceInfo.Band, each a fifth of the
+// day's prices
+const (
+	BandCheapest      = "cheapest"
+	BandCheap         = "cheap"
+	BandAverage       = "average"
+	BandExpensive     = "expensive"
+	BandMostExpensive = "most-expensive"
+)
+
+// PriceBand returns the band of a percentile
+func PriceBand(percentile int) string {
+	switch {
+	case percentile < 20:
+		return BandCheapest
+	case percentile < 40:
+		return BandCheap
+	case percentile < 60:
+		return BandAverage
+	case percentile < 80:
+		return BandExpensive
+	}
+	return BandMostExpensive
+}
+
+// dayKey is the local date of t
+func dayKey(t time.Time) string {
+	return t.Local().Format("2006-01-02")
+}
+
+// dailyStats returns min, max and average prices for each local day in
+// prices, in order
+func dailyStats(prices []store.PriceInfo) []store.DailyPriceStats {
+	var days []store.DailyPriceStats
+	var sum float64
+	for _, p := range prices {
+		key := dayKey(p.StartTime)
+		if len(days) == 0 || days[len(days)-1].Date != key {
+			days = append(days, store.DailyPriceStats{Date: key, Min: p.Price, Max: p.Price, MinTime: p.StartTime, MaxTime: p.StartTime})
+			sum = 0
+		}
+		d := &days[len(days)-1]
+		if p.Price < d.Min {
+			d.Min, d.MinTime = p.Price, p.StartTime
+		}
+		if p.Price > d.Max {
+			d.Max, d.MaxTime = p.Price, p.StartTime
+		}
+		sum += p.Price
+		d.Slots++
+		d.Average = round3(sum / float64(d.Slots))
+	}
+	return days
+}
+
+// withPercentiles ranks each slot among all prices of the same local day
+func withPercentiles(slots, all []store.PriceInfo) {
+	byDay := make(map[string][]float64)
+	for _, p := range all {
+		key := dayKey(p.StartTime)
+		byDay[key] = append(byDay[key], p.Price)
+	}
+	for _, day := range byDay {
+		sort.Float64s(day)
+	}
+	for i := range slots {
+		day := byDay[dayKey(slots[i].StartTime)]
+		if len(day) == 0 {
+			continue
+		}
+		// Midpoint rank, so equal prices share a percentile
+		below := sort.SearchFloat64s(day, slots[i].Price)
+		equal := sort.SearchFloat64s(day, math.Nextafter(slots[i].Price, math.Inf(1))) - below
+		pct := int(100 * (float64(below) + float64(equal)/2) / float64(len(day)))
+		slots[i].Percentile = pct
+		slots[i].Band = PriceBand(pct)
+	}
+}
+
+// CheapestWindow finds the contiguous run of price slots lasting d with the
+// lowest average price, among slots that have not ended by now. The earliest
+// of equally cheap windows wins; ok is false if no run is long enough.
+func CheapestWindow(prices []store.PriceInfo, d time.Duration, now time.Time) (window store.PriceWindow, ok bool) {
+	n := int(d / config.PriceSlot)
+	if n <= 0 {
+		return store.PriceWindow{}, false
+	}
+
+	var future []store.PriceInfo
+	for _, p := range prices {
+		if p.EndTime.After(now) {
+			future = append(future, p)
+		}
+	}
+
+	best := math.Inf(1)
+	runStart := 0 // First slot of the current gapless run
+	var sum float64
+	for i, p := range future {
+		if i > 0 && !p.StartTime.Equal(future[i-1].EndTime) {
+			runStart, sum = i, 0
+		}
+		sum += p.Price
+		if i-runStart+1 > n {
+			sum -= future[i-n].Price
+		}
+		if i-runStart+1 >= n && sum < best {
+			best = sum
+			window = store.PriceWindow{
+				Duration: int(d.Seconds()),
+				Start:    future[i-n+1].StartTime,
+				End:      p.EndTime,
+				Average:  round3(sum / float64(n)),
+			}
+			ok = true
+		}
+	}
+	return window, ok
+}
+
+// ApplianceWindows finds the cheapest window for each appliance. Appliances
+// longer than the known prices are left out.
+func ApplianceWindows(prices []store.PriceInfo, appliances []config.Appliance, now time.Time) []store.PriceWindow {
+	var windows []store.PriceWindow
+	for _, a := range appliances {
+		if w, ok := CheapestWindow(prices, a.Duration.Duration, now); ok {
+			w.Name = a.Name
+			windows = append(windows, w)
+		}
+	}
+	return windows
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
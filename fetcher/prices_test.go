@@ -0,0 +1,154 @@
+package fetcher
+
+import (
+	"rasp_info/config"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+// priceSlots returns consecutive 15 minute slots starting at start, in c/kWh
+func priceSlots(start time.Time, prices ...float64) []store.PriceInfo {
+	var out []store.PriceInfo
+	for i, p := range prices {
+		t := start.Add(time.Duration(i) * 15 * time.Minute)
+		out = append(out, store.PriceInfo{Price: p, StartTime: t, EndTime: t.Add(15 * time.Minute)})
+	}
+	return out
+}
+
+func TestCheapestWindow(t *testing.T) {
+	start := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	gap := append(priceSlots(start, 1, 1, 1), priceSlots(start.Add(time.Hour), 1, 9, 9, 9)...)
+
+	tests := []struct {
+		name      string
+		prices    []store.PriceInfo
+		d         time.Duration
+		now       time.Time
+		wantOK    bool
+		wantStart time.Time
+		wantAvg   float64
+	}{
+		{
+			name:      "cheapest half hour",
+			prices:    priceSlots(start, 5, 4, 3, 1, 2, 8),
+			d:         30 * time.Minute,
+			now:       start,
+			wantOK:    true,
+			wantStart: start.Add(45 * time.Minute),
+			wantAvg:   1.5,
+		},
+		{
+			name:      "earliest of equal windows",
+			prices:    priceSlots(start, 2, 2, 2, 2),
+			d:         30 * time.Minute,
+			now:       start,
+			wantOK:    true,
+			wantStart: start,
+			wantAvg:   2,
+		},
+		{
+			name:      "past slots skipped",
+			prices:    priceSlots(start, 0, 0, 5, 6, 7),
+			d:         30 * time.Minute,
+			now:       start.Add(31 * time.Minute),
+			wantOK:    true,
+			wantStart: start.Add(30 * time.Minute),
+			wantAvg:   5.5,
+		},
+		{
+			name:      "window does not span a gap",
+			prices:    gap,
+			d:         time.Hour,
+			now:       start,
+			wantOK:    true,
+			wantStart: start.Add(time.Hour),
+			wantAvg:   7,
+		},
+		{
+			name:   "longer than known prices",
+			prices: priceSlots(start, 1, 2),
+			d:      time.Hour,
+			now:    start,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, ok := CheapestWindow(tt.prices, tt.d, tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !w.Start.Equal(tt.wantStart) || !w.End.Equal(tt.wantStart.Add(tt.d)) || w.Average != tt.wantAvg {
+				t.Errorf("window = %s-%s at %v, want %s at %v", w.Start, w.End, w.Average, tt.wantStart, tt.wantAvg)
+			}
+			if w.Duration != int(tt.d.Seconds()) {
+				t.Errorf("duration = %d", w.Duration)
+			}
+		})
+	}
+}
+
+func TestApplianceWindowsOnRead(t *testing.T) {
+	appliances := []config.Appliance{
+		{Name: "Dishwasher", Duration: config.Duration{Duration: 30 * time.Minute}},
+		{Name: "Sauna", Duration: config.Duration{Duration: 3 * time.Hour}},
+	}
+	st := store.New()
+	st.SetWindowFunc(func(prices []store.PriceInfo, now time.Time) []store.PriceWindow {
+		return ApplianceWindows(prices, appliances, now)
+	})
+
+	// Prices as fetched an hour ago: the cheapest slots have passed since
+	now := time.Now()
+	slot := now.Truncate(config.PriceSlot)
+	st.UpdateElectricity(store.ElectricityData{
+		Prices: priceSlots(slot.Add(-time.Hour), 1, 1, 1, 1, 6, 5, 3, 3, 9),
+	})
+
+	windows := st.Get().Electricity.Windows
+	if len(windows) != 1 {
+		t.Fatalf("windows = %+v, want only the dishwasher's; the sauna outlasts the prices", windows)
+	}
+	w := windows[0]
+	if w.Name != "Dishwasher" || !w.Start.Equal(slot.Add(30*time.Minute)) || w.Average != 3 {
+		t.Errorf("window = %+v, want the 3 c/kWh half hour starting %s", w, slot.Add(30*time.Minute))
+	}
+}
+
+func TestPriceStats(t *testing.T) {
+	today := time.Date(2026, 10, 16, 22, 0, 0, 0, time.Local)
+	// Four slots before midnight, four after
+	all := priceSlots(today.Add(time.Hour), 4, 2, 8, 6, 10, 10, 1, 3)
+
+	days := dailyStats(all)
+	if len(days) != 2 {
+		t.Fatalf("got %d days, want 2", len(days))
+	}
+	d := days[0]
+	if d.Date != "2026-10-16" || d.Min != 2 || d.Max != 8 || d.Average != 5 || d.Slots != 4 || !d.MinTime.Equal(all[1].StartTime) {
+		t.Errorf("first day = %+v", d)
+	}
+	if d := days[1]; d.Date != "2026-10-17" || d.Min != 1 || d.Max != 10 || !d.MaxTime.Equal(all[4].StartTime) {
+		t.Errorf("second day = %+v", d)
+	}
+
+	slots := append([]store.PriceInfo(nil), all...)
+	withPercentiles(slots, all)
+	want := []struct {
+		percentile int
+		band       string
+	}{
+		{37, BandCheap}, {12, BandCheapest}, {87, BandMostExpensive}, {62, BandExpensive}, // 4, 2, 8, 6
+		{75, BandExpensive}, {75, BandExpensive}, {12, BandCheapest}, {37, BandCheap}, // 10, 10, 1, 3
+	}
+	for i, w := range want {
+		if slots[i].Percentile != w.percentile || slots[i].Band != w.band {
+			t.Errorf("slot %d (%v) = %d %s, want %d %s", i, slots[i].Price, slots[i].Percentile, slots[i].Band, w.percentile, w.band)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Replay transport modes
+const (
+	ModeRecord = "record" // Forward requests and save the responses
+	ModeReplay = "replay" // Answer from saved responses only
+)
+
+// Fixture is one recorded HTTP exchange as stored on disk
+type Fixture struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	RequestBody string      `json:"request_body,omitempty"`
+	Status      int         `json:"status"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// ReplayTransport is an http.RoundTripper that records responses to fixture
+// files or replays them, so fetchers can be tested and developed offline.
+// Request headers are not recorded, so API keys never end up in fixtures.
+type ReplayTransport struct {
+	Dir  string
+	Mode string
+	Base http.RoundTripper // Used when recording; defaults to http.DefaultTransport
+
+	// Query parameters left out when matching requests, e.g. the FMI
+	// starttime and endtime that change on every fetch
+	IgnoreParams []string
+
+	// Leave out GraphQL variables holding a date, clock time or Unix
+	// timestamp, e.g. the departure start time and planner date and time
+	IgnoreTimeVariables bool
+}
+
+// NewReplayClient returns an HTTPClient that records to or replays from dir.
+// FMI start and end times and GraphQL time variables are ignored when
+// matching.
+func NewReplayClient(dir, mode string, base *HTTPClient) *HTTPClient {
+	rt := &ReplayTransport{Dir: dir, Mode: mode, IgnoreParams: []string{"starttime", "endtime"}, IgnoreTimeVariables: true}
+	c := &HTTPClient{Client: &http.Client{Transport: rt}}
+	if base != nil {
+		c.Config = base.Config
+	}
+	return c
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	path := filepath.Join(t.Dir, t.fixtureName(req, reqBody))
+
+	switch t.Mode {
+	case ModeReplay:
+		fx, err := LoadFixture(path)
+		if err != nil {
+			return nil, fmt.Errorf("no fixture for %s %s: %w", req.Method, req.URL, err)
+		}
+		return fx.response(req), nil
+	case ModeRecord:
+		return t.record(req, reqBody, path)
+	}
+	return nil, fmt.Errorf("unknown replay mode %q", t.Mode)
+}
+
+func (t *ReplayTransport) record(req *http.Request, reqBody []byte, path string) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	// Always record full responses, never a 304 for a client-side cache
+	req = req.Clone(req.Context())
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+	req.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// Fixtures hold plain text so they can be read and edited
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		defer gz.Close()
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	header := resp.Header.Clone()
+	header.Del("Content-Encoding")
+	header.Del("Content-Length")
+
+	fx := Fixture{
+		Method:      req.Method,
+		URL:         req.URL.String(),
+		RequestBody: string(reqBody),
+		Status:      resp.StatusCode,
+		Header:      header,
+		Body:        string(data),
+	}
+	if err := fx.Save(path); err != nil {
+		return nil, err
+	}
+	return fx.response(req), nil
+}
+
+// fixtureName derives a stable file name from the host and a hash of the
+// method, the URL without ignored parameters and the request body without
+// ignored variables
+func (t *ReplayTransport) fixtureName(req *http.Request, body []byte) string {
+	u := *req.URL
+	q := u.Query()
+	for _, p := range t.IgnoreParams {
+		q.Del(p)
+	}
+	u.RawQuery = q.Encode()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, u.String())
+	if t.IgnoreTimeVariables {
+		body = withoutTimeVariables(body)
+	}
+	h.Write(body)
+	host := strings.NewReplacer(":", "_", ".", "_").Replace(u.Host)
+	return fmt.Sprintf("%s_%s.json", host, hex.EncodeToString(h.Sum(nil))[:12])
+}
+
+// withoutTimeVariables blanks the time-valued variables of a GraphQL
+// request body. Other bodies are returned as is.
+func withoutTimeVariables(body []byte) []byte {
+	var req struct {
+		Query     string                     `json:"query"`
+		Variables map[string]json.RawMessage `json:"variables"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil || req.Query == "" {
+		return body
+	}
+	for name, raw := range req.Variables {
+		if isTimeValue(raw) {
+			req.Variables[name] = json.RawMessage(`null`)
+		}
+	}
+	out, err := json.Marshal(req)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// isTimeValue reports whether a JSON value is a date ("2006-01-02"), a
+// clock time ("15:04:05"), an RFC 3339 time or a Unix timestamp in seconds
+// or milliseconds from 2001 on
+func isTimeValue(raw json.RawMessage) bool {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		for _, layout := range []string{"2006-01-02", "15:04:05", "15:04", time.RFC3339} {
+			if _, err := time.Parse(layout, s); err == nil {
+				return true
+			}
+		}
+		return false
+	}
+	var n int64
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n >= 1e9 && n < 1e11 || n >= 1e12 && n < 1e14
+	}
+	return false
+}
+
+// LoadFixture reads a fixture file
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", path, err)
+	}
+	return &fx, nil
+}
+
+// Save writes the fixture to path, creating its directory
+func (fx *Fixture) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture dir: %w", err)
+	}
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (fx *Fixture) response(req *http.Request) *http.Response {
+	header := fx.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", fx.Status, http.StatusText(fx.Status)),
+		StatusCode:    fx.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(fx.Body)),
+		ContentLength: int64(len(fx.Body)),
+		Request:       req,
+	}
+}
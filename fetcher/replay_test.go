@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplayTransport(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, []byte(`[{"PriceWithTax":0.05}]`))
+	dir := t.TempDir()
+	url := srv.URL + "/prices?starttime=a&region=FI"
+
+	base := NewHTTPClient(testConfig(nil)) // No retries
+	rec := NewReplayClient(dir, ModeRecord, base)
+	resp, err := rec.Get(context.Background(), url, http.Header{"Digitransit-Subscription-Key": {"secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(resp.Body) != `[{"PriceWithTax":0.05}]` {
+		t.Fatalf("recorded body = %q", resp.Body)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("want one fixture, got %v (%v)", entries, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("fixture must not contain request headers")
+	}
+
+	srv.Close()
+	play := NewReplayClient(dir, ModeReplay, base)
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{name: "same request", url: url},
+		{name: "ignored param differs", url: srv.URL + "/prices?starttime=b&region=FI"},
+		{name: "other param differs", url: srv.URL + "/prices?starttime=a&region=SE", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := play.Get(context.Background(), tt.url, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && string(resp.Body) != `[{"PriceWithTax":0.05}]` {
+				t.Errorf("replayed body = %q", resp.Body)
+			}
+		})
+	}
+}
+
+func TestReplayTransportGraphQLTimeVariables(t *testing.T) {
+	srv := newTestServer(t, http.StatusOK, []byte(`{"data":{"stop":{"name":"Kamppi"}}}`))
+	dir := t.TempDir()
+	query := "query ($v0: String!, $v1: Long, $v2: String, $v3: String) { stop(id: $v0) { name } }"
+	vars := func(stop string, start int64, date, clock string) map[string]any {
+		return map[string]any{"v0": stop, "v1": start, "v2": date, "v3": clock}
+	}
+
+	base := NewHTTPClient(testConfig(nil))
+	rec := &GraphQLClient{URL: srv.URL, HTTP: NewReplayClient(dir, ModeRecord, base)}
+	if err := rec.Do(context.Background(), query, vars("HSL:1", 1792130400, "2026-10-16", "08:00:00"), nil); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+	play := &GraphQLClient{URL: srv.URL, HTTP: NewReplayClient(dir, ModeReplay, base)}
+
+	tests := []struct {
+		name    string
+		vars    map[string]any
+		wantErr bool
+	}{
+		{name: "same request", vars: vars("HSL:1", 1792130400, "2026-10-16", "08:00:00")},
+		{name: "time variables differ", vars: vars("HSL:1", 1792216800, "2026-10-17", "17:30:00")},
+		{name: "other variable differs", vars: vars("HSL:2", 1792130400, "2026-10-16", "08:00:00"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out struct {
+				Stop struct{ Name string }
+			}
+			err := play.Do(context.Background(), query, tt.vars, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && out.Stop.Name != "Kamppi" {
+				t.Errorf("replayed stop = %q", out.Stop.Name)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StopResolution is the cached result of resolving a stop short code
+type StopResolution struct {
+	Code       string    `json:"code"`
+	ID         string    `json:"id,omitempty"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	Error      string    `json:"error,omitempty"` // Last failed lookup, if any
+	FailedAt   time.Time `json:"failed_at,omitzero"`
+}
+
+// failureTTL is how long a failed lookup is remembered before the geocoding
+// API is asked again, so a bad code or an outage does not cost a request on
+// every fetch
+const failureTTL = 5 * time.Minute
+
+// lookupTimeout bounds one geocoding lookup. Lookups are serialized, so a
+// hung request would otherwise block every other resolution behind it.
+const lookupTimeout = 10 * time.Second
+
+// StopCache remembers short code -> GTFS id resolutions in memory and on
+// disk, so the geocoding API is called once per code per TTL instead of on
+// every fetch.
+type StopCache struct {
+	Path   string // Empty disables persistence
+	Lookup func(ctx context.Context, code string) (string, error)
+
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]StopResolution
+
+	lookupMu sync.Mutex // Serializes lookups so concurrent misses hit the API once
+}
+
+// NewStopCache creates a cache and loads previous resolutions from path
+func NewStopCache(path string, ttl time.Duration, lookup func(ctx context.Context, code string) (string, error)) *StopCache {
+	c := &StopCache{
+		Path:    path,
+		ttl:     ttl,
+		Lookup:  lookup,
+		entries: make(map[string]StopResolution),
+	}
+	if path == "" {
+		return c
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading stop cache: %v", err)
+		}
+		return c
+	}
+	var entries []StopResolution
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("Error decoding stop cache: %v", err)
+		return c
+	}
+	for _, e := range entries {
+		if e.ID != "" {
+			c.entries[e.Code] = e
+		}
+	}
+	return c
+}
+
+// Resolve returns the GTFS id for a stop. Full ids (HSL:...) are returned as
+// is; short codes come from the cache or the geocoding API. If a lookup fails
+// but an expired resolution exists, the expired one is used. Failures are
+// cached for failureTTL, unless ctx was cancelled.
+func (c *StopCache) Resolve(ctx context.Context, code string) (string, error) {
+	if strings.HasPrefix(code, "HSL:") {
+		return code, nil
+	}
+	if id, ok := c.fresh(code); ok {
+		return id, nil
+	}
+
+	c.lookupMu.Lock()
+	defer c.lookupMu.Unlock()
+	if id, ok := c.fresh(code); ok {
+		return id, nil // Resolved while we waited
+	}
+	if e, ok := c.failed(code); ok {
+		if e.ID != "" {
+			return e.ID, nil
+		}
+		return "", fmt.Errorf("failed to resolve stop %s: %s", code, e.Error)
+	}
+
+	log.Printf("Resolving stop code via geocoding API: %s", code)
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	id, err := c.Lookup(lookupCtx, code)
+	cancel()
+
+	c.mu.Lock()
+	prev := c.entries[code]
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up, e.g. on shutdown; the code may well be fine
+		c.mu.Unlock()
+		return "", fmt.Errorf("failed to resolve stop %s: %w", code, err)
+	}
+	if err != nil {
+		prev.Code = code
+		prev.Error = err.Error()
+		prev.FailedAt = time.Now()
+		c.entries[code] = prev
+		c.mu.Unlock()
+		if prev.ID != "" {
+			log.Printf("Failed to refresh stop %s, using cached id %s: %v", code, prev.ID, err)
+			return prev.ID, nil
+		}
+		return "", fmt.Errorf("failed to resolve stop %s: %w", code, err)
+	}
+	c.entries[code] = StopResolution{Code: code, ID: id, ResolvedAt: time.Now()}
+	c.mu.Unlock()
+
+	log.Printf("Resolved %s to GTFS id %s", code, id)
+	if err := c.save(); err != nil {
+		log.Printf("Error saving stop cache: %v", err)
+	}
+	return id, nil
+}
+
+// SetTTL changes how long resolutions are trusted
+func (c *StopCache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttl = ttl
+}
+
+// ResolveAll resolves every code, logging failures. Used to warm the cache
+// when the config is loaded. It stops early if ctx is cancelled.
+func (c *StopCache) ResolveAll(ctx context.Context, codes []string) {
+	for _, code := range codes {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := c.Resolve(ctx, code); err != nil {
+			log.Printf("%v", err)
+		}
+	}
+}
+
+// Entries returns all known resolutions, sorted by code
+func (c *StopCache) Entries() []StopResolution {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]StopResolution, 0, len(c.entries))
+	for _, e := range c.entries {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+func (c *StopCache) fresh(code string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[code]
+	if !ok || e.ID == "" || time.Since(e.ResolvedAt) > c.ttl {
+		return "", false
+	}
+	return e.ID, true
+}
+
+// failed returns the entry for code if its last lookup failed recently
+func (c *StopCache) failed(code string) (StopResolution, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[code]
+	return e, ok && e.Error != "" && time.Since(e.FailedAt) < failureTTL
+}
+
+// save atomically writes successful resolutions to Path
+func (c *StopCache) save() error {
+	if c.Path == "" {
+		return nil
+	}
+	var entries []StopResolution
+	for _, e := range c.Entries() {
+		if e.ID != "" {
+			e.Error, e.FailedAt = "", time.Time{}
+			entries = append(entries, e)
+		}
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.Path), filepath.Base(c.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.Path)
+}
@@ -0,0 +1,98 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStopCacheFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		failedAgo   time.Duration
+		prevID      string
+		wantID      string
+		wantErr     bool
+		wantLookups int
+	}{
+		{name: "recent failure is cached", failedAgo: time.Minute, wantErr: true, wantLookups: 0},
+		{name: "recent failure keeps expired id", failedAgo: time.Minute, prevID: "HSL:1", wantID: "HSL:1", wantLookups: 0},
+		{name: "old failure is retried", failedAgo: failureTTL + time.Minute, wantID: "HSL:2", wantLookups: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups := 0
+			c := NewStopCache("", time.Hour, func(context.Context, string) (string, error) {
+				lookups++
+				return "HSL:2", nil
+			})
+			c.entries["E1"] = StopResolution{
+				Code:       "E1",
+				ID:         tt.prevID,
+				ResolvedAt: time.Now().Add(-2 * time.Hour),
+				Error:      "geocoding down",
+				FailedAt:   time.Now().Add(-tt.failedAgo),
+			}
+
+			id, err := c.Resolve(context.Background(), "E1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.wantID || lookups != tt.wantLookups {
+				t.Errorf("id = %q after %d lookups, want %q after %d", id, lookups, tt.wantID, tt.wantLookups)
+			}
+		})
+	}
+}
+
+func TestStopCacheRemembersFailure(t *testing.T) {
+	lookups := 0
+	c := NewStopCache("", time.Hour, func(context.Context, string) (string, error) {
+		lookups++
+		return "", errors.New("no such stop")
+	})
+	for range 3 {
+		if _, err := c.Resolve(context.Background(), "E9999"); err == nil {
+			t.Fatal("want an error for an unknown stop")
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("lookups = %d, want 1", lookups)
+	}
+}
+
+func TestStopCacheCancelledLookup(t *testing.T) {
+	lookups := 0
+	c := NewStopCache("", time.Hour, func(ctx context.Context, code string) (string, error) {
+		lookups++
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > lookupTimeout {
+			t.Errorf("lookup deadline = %v (set %v), want within %s", deadline, ok, lookupTimeout)
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		return "HSL:2", nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.Resolve(ctx, "E1234"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if e, ok := c.failed("E1234"); ok {
+		t.Errorf("cancelled lookup was cached as a failure: %+v", e)
+	}
+
+	id, err := c.Resolve(context.Background(), "E1234")
+	if err != nil || id != "HSL:2" || lookups != 2 {
+		t.Errorf("after cancel: id = %q, err = %v after %d lookups, want HSL:2 after 2", id, err, lookups)
+	}
+
+	// Warming the cache stops at once when cancelled
+	c.ResolveAll(ctx, []string{"E5678", "E9012"})
+	if lookups != 2 {
+		t.Errorf("ResolveAll with a cancelled context made %d lookups", lookups-2)
+	}
+}
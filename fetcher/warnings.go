@@ -0,0 +1,378 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"rasp_info/config"
+	"rasp_info/store"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WarningsFetcher reads FMI's weather warnings from their CAP Atom feed
+type WarningsFetcher struct {
+	Config *config.Manager
+	Store  *store.Store
+	HTTP   *HTTPClient // Shared HTTP layer; nil uses defaults
+}
+
+// capAlert is a Common Alerting Protocol 1.2 message
+type capAlert struct {
+	Identifier string    `xml:"identifier"`
+	Status     string    `xml:"status"`  // Actual, Exercise, Test, ...
+	MsgType    string    `xml:"msgType"` // Alert, Update or Cancel
+	References string    `xml:"references"`
+	Info       []capInfo `xml:"info"` // One per language
+}
+
+type capInfo struct {
+	Language    string    `xml:"language"`
+	Event       string    `xml:"event"`
+	Severity    string    `xml:"severity"`
+	Effective   string    `xml:"effective"`
+	Onset       string    `xml:"onset"`
+	Expires     string    `xml:"expires"`
+	Headline    string    `xml:"headline"`
+	Description string    `xml:"description"`
+	Web         string    `xml:"web"`
+	Areas       []capArea `xml:"area"`
+}
+
+type capArea struct {
+	Desc     string   `xml:"areaDesc"`
+	Polygons []string `xml:"polygon"` // "lat,lon lat,lon ..."
+}
+
+// atomEntry is one warning in the feed. FMI embeds the CAP message; if it
+// is missing, the linked CAP document is fetched instead.
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Links []struct {
+		Type string `xml:"type,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Alert *capAlert `xml:"content>alert"`
+}
+
+func (f *WarningsFetcher) Fetch(ctx context.Context) error {
+	cfg := f.Config.Get()
+	weather := f.Store.Get().Weather
+	if len(cfg.WarningRegions) == 0 && weather.FetchedAt.IsZero() {
+		// Locations without coordinates are matched by the coordinates the
+		// first weather fetch resolves for them
+		for _, p := range cfg.WeatherPlaces() {
+			if _, _, ok := p.Coordinates(); !ok {
+				return fmt.Errorf("%w: no coordinates for %s", ErrNotReady, p.Name)
+			}
+		}
+	}
+
+	var alerts []capAlert
+	var links []string
+	err := f.HTTP.Stream(ctx, cfg.WarningsAPIUrl, nil, func(status int, body io.Reader) error {
+		if status != http.StatusOK {
+			return fmt.Errorf("warnings feed returned status: %d", status)
+		}
+		var err error
+		alerts, links, err = decodeWarningFeed(body)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch weather warnings: %w", err)
+	}
+
+	// A warning missing from a partial list would look like it had ended,
+	// so anything but a bad document fails the fetch and keeps the warnings
+	// already shown
+	for _, link := range links {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("failed to fetch weather warnings: %w", err)
+		}
+		alert, err := f.fetchAlert(ctx, link)
+		if errors.Is(err, errBadAlert) {
+			log.Printf("Warnings: Skipping %s: %v", link, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to fetch weather warning %s: %w", link, err)
+		}
+		alerts = append(alerts, *alert)
+	}
+
+	region := newWarningRegion(cfg, weather.Locations)
+	warnings := selectWarnings(alerts, region, time.Now())
+	log.Printf("Warnings: %d of %d warnings apply", len(warnings), len(alerts))
+
+	f.Store.UpdateWarnings(store.WarningsData{
+		SectionMeta: store.SectionMeta{Source: "fmi"},
+		Warnings:    warnings,
+	})
+	return nil
+}
+
+// errBadAlert is returned, wrapped, for a linked CAP document that is gone
+// or cannot be decoded. Fetching it again would not help.
+var errBadAlert = errors.New("bad CAP document")
+
+// fetchAlert fetches a CAP document linked from the feed
+func (f *WarningsFetcher) fetchAlert(ctx context.Context, url string) (*capAlert, error) {
+	resp, err := f.HTTP.Get(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%w: status: %d", errBadAlert, resp.StatusCode)
+	default:
+		return nil, fmt.Errorf("status: %d", resp.StatusCode)
+	}
+	var alert capAlert
+	if err := xml.Unmarshal(resp.Body, &alert); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode CAP XML: %v", errBadAlert, err)
+	}
+	return &alert, nil
+}
+
+// decodeWarningFeed reads the Atom feed one entry at a time. It returns the
+// embedded alerts and the CAP links of entries without one.
+func decodeWarningFeed(r io.Reader) ([]capAlert, []string, error) {
+	dec := xml.NewDecoder(r)
+	var root string
+	var alerts []capAlert
+	var links []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode warnings feed: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if root == "" {
+			root = start.Name.Local
+			if root != "feed" {
+				return nil, nil, fmt.Errorf("unexpected warnings response <%s>", root)
+			}
+			continue
+		}
+		if start.Name.Local != "entry" {
+			continue
+		}
+
+		var entry atomEntry
+		if err := dec.DecodeElement(&entry, &start); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode warnings feed: %w", err)
+		}
+		if entry.Alert != nil {
+			alerts = append(alerts, *entry.Alert)
+			continue
+		}
+		for _, l := range entry.Links {
+			if l.Type == "application/cap+xml" {
+				links = append(links, l.Href)
+				break
+			}
+		}
+	}
+	if root == "" {
+		return nil, nil, fmt.Errorf("failed to decode warnings feed: no XML document")
+	}
+	return alerts, links, nil
+}
+
+// warningRegion decides which warning areas are shown: those named in
+// warning_regions, or else those named after or covering a weather location
+type warningRegion struct {
+	names  []string     // Lower case area names
+	points [][2]float64 // Latitude, longitude
+}
+
+func newWarningRegion(cfg *config.Config, locations []store.LocationWeather) warningRegion {
+	var r warningRegion
+	if len(cfg.WarningRegions) > 0 {
+		for _, name := range cfg.WarningRegions {
+			r.names = append(r.names, strings.ToLower(name))
+		}
+		return r
+	}
+	for _, p := range cfg.WeatherPlaces() {
+		if p.Place != "" {
+			r.names = append(r.names, strings.ToLower(p.Place))
+		}
+		if lat, lon, ok := p.Coordinates(); ok {
+			r.points = append(r.points, [2]float64{lat, lon})
+		}
+	}
+	// Coordinates FMI resolved for place names and stations
+	for _, l := range locations {
+		if l.Lat != 0 || l.Lon != 0 {
+			r.points = append(r.points, [2]float64{l.Lat, l.Lon})
+		}
+	}
+	return r
+}
+
+// matches reports whether an area is in the region
+func (r warningRegion) matches(a capArea) bool {
+	desc := strings.ToLower(strings.TrimSpace(a.Desc))
+	for _, name := range r.names {
+		if desc == name {
+			return true
+		}
+	}
+	for _, polygon := range a.Polygons {
+		ring := parsePolygon(polygon)
+		for _, p := range r.points {
+			if inPolygon(p, ring) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectWarnings converts the actual, unexpired alerts affecting the region,
+// most severe first. Alerts replaced by an update or cancellation are
+// dropped.
+func selectWarnings(alerts []capAlert, region warningRegion, now time.Time) []store.WeatherWarning {
+	replaced := make(map[string]bool)
+	for _, a := range alerts {
+		// "sender,identifier,sent" triplets separated by spaces
+		for _, ref := range strings.Fields(a.References) {
+			if parts := strings.Split(ref, ","); len(parts) == 3 {
+				replaced[parts[1]] = true
+			}
+		}
+	}
+
+	var warnings []store.WeatherWarning
+	for _, a := range alerts {
+		if a.Status != "Actual" || a.MsgType == "Cancel" || replaced[a.Identifier] || len(a.Info) == 0 {
+			continue
+		}
+		w := store.WeatherWarning{
+			ID:          a.Identifier,
+			Severity:    a.Info[0].Severity,
+			Type:        make(map[string]string),
+			Headline:    make(map[string]string),
+			Description: make(map[string]string),
+		}
+		for _, info := range a.Info {
+			lang := languageCode(info.Language)
+			setText(w.Type, lang, info.Event)
+			setText(w.Headline, lang, info.Headline)
+			setText(w.Description, lang, info.Description)
+			if w.URL == "" {
+				w.URL = info.Web
+			}
+			if w.ValidFrom.IsZero() {
+				w.ValidFrom = parseCAPTime(info.Onset, info.Effective)
+			}
+			if w.ValidTo.IsZero() {
+				w.ValidTo = parseCAPTime(info.Expires)
+			}
+			// Area names differ by language; report those of the first
+			// language that matches
+			if len(w.Areas) == 0 {
+				for _, area := range info.Areas {
+					if region.matches(area) {
+						w.Areas = append(w.Areas, area.Desc)
+					}
+				}
+			}
+		}
+		if len(w.Areas) == 0 || !w.ValidTo.IsZero() && w.ValidTo.Before(now) {
+			continue
+		}
+		warnings = append(warnings, w)
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		ri, rj := capSeverityRank(warnings[i].Severity), capSeverityRank(warnings[j].Severity)
+		if ri != rj {
+			return ri > rj
+		}
+		return warnings[i].ValidFrom.Before(warnings[j].ValidFrom)
+	})
+	return warnings
+}
+
+func capSeverityRank(s string) int {
+	switch s {
+	case "Extreme":
+		return 4
+	case "Severe":
+		return 3
+	case "Moderate":
+		return 2
+	case "Minor":
+		return 1
+	}
+	return 0
+}
+
+// languageCode shortens a CAP language such as "fi-FI" to "fi", matching
+// the language keys of transport alerts
+func languageCode(lang string) string {
+	code, _, _ := strings.Cut(lang, "-")
+	return strings.ToLower(code)
+}
+
+func setText(m map[string]string, lang, text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		m[lang] = text
+	}
+}
+
+// parseCAPTime returns the first of values that is a valid time
+func parseCAPTime(values ...string) time.Time {
+	for _, v := range values {
+		if t, err := time.Parse(time.RFC3339, strings.TrimSpace(v)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parsePolygon parses a CAP polygon of "lat,lon" pairs
+func parsePolygon(s string) [][2]float64 {
+	var ring [][2]float64
+	for _, pair := range strings.Fields(s) {
+		latStr, lonStr, ok := strings.Cut(pair, ",")
+		if !ok {
+			continue
+		}
+		lat, err1 := strconv.ParseFloat(latStr, 64)
+		lon, err2 := strconv.ParseFloat(lonStr, 64)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		ring = append(ring, [2]float64{lat, lon})
+	}
+	return ring
+}
+
+// inPolygon reports whether p lies inside ring, by ray casting
+func inPolygon(p [2]float64, ring [][2]float64) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > p[1]) != (b[1] > p[1]) &&
+			p[0] < (b[0]-a[0])*(p[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,249 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rasp_info/config"
+	"rasp_info/store"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWarningsFetcher(t *testing.T) {
+	const (
+		wind    = "2.49.0.1.246.0.0.2026.10.16.wind.1"
+		traffic = "2.49.0.1.246.0.0.2026.10.16.traffic.2"
+		fire    = "2.49.0.1.246.0.0.2026.10.16.fire.1"
+	)
+
+	tests := []struct {
+		name      string
+		status    int
+		regions   []string
+		places    []config.WeatherPlace
+		locations []store.LocationWeather // Already fetched weather
+		noWeather bool                    // Weather not fetched yet
+		wantErr   bool
+		wantIDs   []string
+	}{
+		{
+			name:    "covering coordinates, most severe first",
+			status:  http.StatusOK,
+			places:  []config.WeatherPlace{{Name: "Home", LatLon: "60.205,24.655"}},
+			wantIDs: []string{traffic, wind},
+		},
+		{
+			name:    "place name matches area",
+			status:  http.StatusOK,
+			places:  []config.WeatherPlace{{Name: "Home", Place: "espoo"}},
+			wantIDs: []string{traffic},
+		},
+		{
+			name:      "coordinates resolved by FMI",
+			status:    http.StatusOK,
+			places:    []config.WeatherPlace{{Name: "Home", FMISID: "100971"}},
+			locations: []store.LocationWeather{{Name: "Home", Lat: 60.12, Lon: 24.44}},
+			wantIDs:   []string{wind},
+		},
+		{
+			name:      "coordinates not resolved yet",
+			status:    http.StatusOK,
+			places:    []config.WeatherPlace{{Name: "Home", FMISID: "100971"}},
+			noWeather: true,
+			wantErr:   true,
+		},
+		{
+			name:      "configured regions need no coordinates",
+			status:    http.StatusOK,
+			regions:   []string{"Lapland"},
+			places:    []config.WeatherPlace{{Name: "Home", FMISID: "100971"}},
+			noWeather: true,
+			wantIDs:   []string{fire},
+		},
+		{
+			name:    "configured regions override locations",
+			status:  http.StatusOK,
+			regions: []string{"Lapland"},
+			places:  []config.WeatherPlace{{Name: "Home", LatLon: "60.205,24.655"}},
+			wantIDs: []string{fire},
+		},
+		{
+			name:    "nothing in region",
+			status:  http.StatusOK,
+			places:  []config.WeatherPlace{{Name: "Home", Place: "Turku"}},
+			wantIDs: nil,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusInternalServerError,
+			places:  []config.WeatherPlace{{Name: "Home", Place: "Espoo"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.status, readTestdata(t, "fmi_warnings.xml"))
+			cfg := testConfig(func(c *config.Config) {
+				c.WarningsAPIUrl = srv.URL
+				c.WarningRegions = tt.regions
+				c.WeatherLocations = tt.places
+			})
+			st := store.New()
+			if !tt.noWeather {
+				st.UpdateWeather(store.WeatherData{Locations: tt.locations})
+			}
+			f := &WarningsFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+			err := f.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && tt.noWeather && !errors.Is(err, ErrNotReady) {
+				t.Errorf("Fetch() error = %v, want ErrNotReady", err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			warnings := st.Get().Warnings.Warnings
+			var ids []string
+			for _, w := range warnings {
+				ids = append(ids, w.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("warnings = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Errorf("warnings = %v, want %v", ids, tt.wantIDs)
+					break
+				}
+			}
+
+			for _, w := range warnings {
+				if w.ID != wind {
+					continue
+				}
+				if w.Headline["fi"] != "Keltainen tuulivaroitus: Uusimaa" || w.Headline["en"] != "Yellow wind warning: Uusimaa" {
+					t.Errorf("wind headlines = %v", w.Headline)
+				}
+				if w.Severity != "Moderate" || len(w.Areas) != 1 || w.Areas[0] != "Uusimaa" || w.ValidFrom.IsZero() || w.ValidTo.IsZero() {
+					t.Errorf("wind warning = %+v", w)
+				}
+			}
+		})
+	}
+}
+
+func TestWarningsFetcherLinkedAlert(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><id>urn:oid:1</id><link rel="related" type="application/cap+xml" href="` + srv.URL + `/cap/1.xml"/></entry>
+  <entry><id>urn:oid:2</id><link rel="related" type="application/cap+xml" href="` + srv.URL + `/cap/missing.xml"/></entry>
+</feed>`))
+	})
+	mux.HandleFunc("/cap/1.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">
+  <identifier>1</identifier><status>Actual</status><msgType>Alert</msgType>
+  <info><language>en-GB</language><event>Sea wind warning</event><severity>Moderate</severity>
+    <area><areaDesc>Gulf of Finland</areaDesc></area></info>
+</alert>`))
+	})
+	mux.HandleFunc("/cap/missing.xml", http.NotFound)
+
+	cfg := testConfig(func(c *config.Config) {
+		c.WarningsAPIUrl = srv.URL + "/feed"
+		c.WarningRegions = []string{"Gulf of Finland"}
+	})
+	st := store.New()
+	f := &WarningsFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+	// The missing document is skipped
+	if err := f.Fetch(context.Background()); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	warnings := st.Get().Warnings.Warnings
+	if len(warnings) != 1 || warnings[0].Type["en"] != "Sea wind warning" || !warnings[0].ValidTo.IsZero() {
+		t.Errorf("warnings = %+v", warnings)
+	}
+}
+
+func TestWarningsFetcherLinkedAlertFailure(t *testing.T) {
+	var mu sync.Mutex
+	mode := "ok"
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<feed xmlns="http://www.w3.org/2005/Atom">
+  <entry><id>urn:oid:1</id><link rel="related" type="application/cap+xml" href="` + srv.URL + `/cap/1.xml"/></entry>
+  <entry><id>urn:oid:2</id><link rel="related" type="application/cap+xml" href="` + srv.URL + `/cap/2.xml"/></entry>
+</feed>`))
+	})
+	alert := func(id string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			m := mode
+			mu.Unlock()
+			if id == "2" {
+				switch m {
+				case "error":
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				case "slow":
+					select {
+					case <-r.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+					return
+				}
+			}
+			w.Write([]byte(`<alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">
+  <identifier>` + id + `</identifier><status>Actual</status><msgType>Alert</msgType>
+  <info><language>en-GB</language><event>Sea wind warning</event><severity>Moderate</severity>
+    <area><areaDesc>Gulf of Finland</areaDesc></area></info>
+</alert>`))
+		}
+	}
+	mux.HandleFunc("/cap/1.xml", alert("1"))
+	mux.HandleFunc("/cap/2.xml", alert("2"))
+
+	cfg := testConfig(func(c *config.Config) {
+		c.WarningsAPIUrl = srv.URL + "/feed"
+		c.WarningRegions = []string{"Gulf of Finland"}
+	})
+	st := store.New()
+	f := &WarningsFetcher{Config: cfg, Store: st, HTTP: NewHTTPClient(cfg)}
+
+	if err := f.Fetch(context.Background()); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if n := len(st.Get().Warnings.Warnings); n != 2 {
+		t.Fatalf("got %d warnings, want 2", n)
+	}
+
+	for _, m := range []string{"error", "slow"} {
+		t.Run(m, func(t *testing.T) {
+			mu.Lock()
+			mode = m
+			mu.Unlock()
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+
+			if err := f.Fetch(ctx); err == nil {
+				t.Fatal("Fetch() error = nil, want error")
+			}
+			// The earlier warnings stay in place
+			if n := len(st.Get().Warnings.Warnings); n != 2 {
+				t.Errorf("got %d warnings, want 2", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package fetcher
+
+import (
+	"math"
+	"rasp_info/astro"
+	"time"
+)
+
+// Weather symbols reported in store.WeatherDataPoint.Symbol. Symbols that
+// look different at night come in -day and -night variants.
+//
+//	clear-day, clear-night                   WeatherSymbol3 1
+//	partly-cloudy-day, partly-cloudy-night   2
+//	cloudy                                   3
+//	showers-day, showers-night               21-23
+//	light-rain, rain, heavy-rain             31, 32, 33
+//	snow-showers-day, snow-showers-night     41-43
+//	light-snow, snow, heavy-snow             51, 52, 53
+//	thunder                                  61-64
+//	sleet                                    71-73, 81-83
+//	fog                                      91, 92
+const (
+	SymbolClear        = "clear"
+	SymbolPartlyCloudy = "partly-cloudy"
+	SymbolCloudy       = "cloudy"
+	SymbolShowers      = "showers"
+	SymbolLightRain    = "light-rain"
+	SymbolRain         = "rain"
+	SymbolHeavyRain    = "heavy-rain"
+	SymbolSnowShowers  = "snow-showers"
+	SymbolLightSnow    = "light-snow"
+	SymbolSnow         = "snow"
+	SymbolHeavySnow    = "heavy-snow"
+	SymbolThunder      = "thunder"
+	SymbolSleet        = "sleet"
+	SymbolFog          = "fog"
+)
+
+// WeatherSymbol maps an FMI WeatherSymbol3 code to a dashboard symbol,
+// picking the night variant where there is one. Unknown codes return "".
+func WeatherSymbol(code int, night bool) string {
+	var symbol string
+	variants := false
+	switch {
+	case code == 1:
+		symbol, variants = SymbolClear, true
+	case code == 2:
+		symbol, variants = SymbolPartlyCloudy, true
+	case code == 3:
+		symbol = SymbolCloudy
+	case code >= 21 && code <= 23:
+		symbol, variants = SymbolShowers, true
+	case code == 31:
+		symbol = SymbolLightRain
+	case code == 32:
+		symbol = SymbolRain
+	case code == 33:
+		symbol = SymbolHeavyRain
+	case code >= 41 && code <= 43:
+		symbol, variants = SymbolSnowShowers, true
+	case code == 51:
+		symbol = SymbolLightSnow
+	case code == 52:
+		symbol = SymbolSnow
+	case code == 53:
+		symbol = SymbolHeavySnow
+	case code >= 61 && code <= 64:
+		symbol = SymbolThunder
+	case code >= 71 && code <= 73, code >= 81 && code <= 83:
+		symbol = SymbolSleet
+	case code == 91 || code == 92:
+		symbol = SymbolFog
+	default:
+		return ""
+	}
+	if !variants {
+		return symbol
+	}
+	if night {
+		return symbol + "-night"
+	}
+	return symbol + "-day"
+}
+
+// fallbackSymbol guesses a symbol from precipitation when no WeatherSymbol3
+// value is available
+func fallbackSymbol(precip, pop float64) string {
+	if precip > 0.1 || pop > 50 {
+		return SymbolRain
+	}
+	return SymbolCloudy
+}
+
+// isNight reports whether the sun is down at t. Without coordinates it
+// falls back to the dark hours of the local clock.
+func isNight(t time.Time, lat, lon float64) bool {
+	if lat == 0 && lon == 0 {
+		h := t.Local().Hour()
+		return h >= 21 || h < 6
+	}
+	return !astro.IsDaylight(t, lat, lon)
+}
+
+// FeelsLike returns the apparent temperature using FMI's formula: a wind
+// chill term (fitted to wind in m/s) plus the summer simmer index for warm,
+// humid weather
+func FeelsLike(temp, windSpeed, humidity float64) float64 {
+	const a, t0 = 15.0, 37.0
+	chill := a + (1-a/t0)*temp + a/t0*math.Pow(windSpeed+1, 0.16)*(temp-t0)
+	return temp + (chill - temp) + (summerSimmer(temp, humidity) - temp)
+}
+
+// summerSimmer is the summer simmer index, applied above 14.5 °C and faded
+// in over one degree
+func summerSimmer(temp, humidity float64) float64 {
+	const limit = 14.5
+	if temp <= limit || math.IsNaN(humidity) {
+		return temp
+	}
+	rhRef := 0.6 // Roughly the lowest monthly mean humidity in Finnish summer
+	rh := humidity / 100
+	ssi := (1.8*temp - 0.55*(1-rh)*(1.8*temp-26) - 0.55*(1-rhRef)*26) / (1.8 * (1 - 0.55*(1-rhRef)))
+	if temp >= limit+1 {
+		return ssi
+	}
+	w := temp - limit
+	return w*ssi + (1-w)*temp
+}
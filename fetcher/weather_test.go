@@ -0,0 +1,76 @@
+package fetcher
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWeatherSymbol(t *testing.T) {
+	tests := []struct {
+		code  int
+		night bool
+		want  string
+	}{
+		{1, false, "clear-day"},
+		{1, true, "clear-night"},
+		{2, true, "partly-cloudy-night"},
+		{3, true, "cloudy"},
+		{22, false, "showers-day"},
+		{31, false, "light-rain"},
+		{33, true, "heavy-rain"},
+		{42, true, "snow-showers-night"},
+		{52, false, "snow"},
+		{63, false, "thunder"},
+		{72, false, "sleet"},
+		{82, true, "sleet"},
+		{92, false, "fog"},
+		{0, false, ""},
+		{99, false, ""},
+	}
+	for _, tt := range tests {
+		if got := WeatherSymbol(tt.code, tt.night); got != tt.want {
+			t.Errorf("WeatherSymbol(%d, %v) = %q, want %q", tt.code, tt.night, got, tt.want)
+		}
+	}
+}
+
+func TestFeelsLike(t *testing.T) {
+	tests := []struct {
+		name                 string
+		temp, wind, humidity float64
+		wantMin, wantMax     float64
+	}{
+		{"calm and mild", 10, 0, 70, 9.9, 10.1},
+		{"windy frost", -10, 8, 80, -19, -16},
+		{"warm and humid", 25, 1, 90, 25.5, 30},
+		{"warm, humidity missing", 25, 0, math.NaN(), 24.9, 25.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FeelsLike(tt.temp, tt.wind, tt.humidity)
+			if got < tt.wantMin || got > tt.wantMax {
+				t.Errorf("FeelsLike(%v, %v, %v) = %.2f, want %v..%v", tt.temp, tt.wind, tt.humidity, got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestIsNight(t *testing.T) {
+	const lat, lon = 60.17, 24.94 // Helsinki
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{"winter afternoon after sunset", time.Date(2026, 12, 21, 14, 0, 0, 0, time.UTC), true},
+		{"winter noon", time.Date(2026, 12, 21, 10, 0, 0, 0, time.UTC), false},
+		{"midsummer evening", time.Date(2026, 6, 21, 19, 0, 0, 0, time.UTC), false},
+		{"midsummer small hours", time.Date(2026, 6, 21, 22, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := isNight(tt.at, lat, lon); got != tt.want {
+			t.Errorf("%s: isNight(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
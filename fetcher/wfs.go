@@ -0,0 +1,293 @@
+package fetcher
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"rasp_info/config"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FMI WFS stored queries returning one timeseries per parameter and location
+const (
+	ForecastQuery    = "fmi::forecast::harmonie::surface::point::timevaluepair"
+	ObservationQuery = "fmi::observations::weather::timevaluepair"
+)
+
+// WFSError is an OWS ExceptionReport returned by FMI, e.g. for an unknown
+// place or parameter. FMI sends these with status 400 for bad requests and
+// server-side failures alike.
+type WFSError struct {
+	Status int      // HTTP status
+	Code   string   // exceptionCode, e.g. "InvalidParameterValue"
+	Texts  []string // ExceptionText entries
+}
+
+func (e *WFSError) Error() string {
+	msg := fmt.Sprintf("fmi: %s (status %d)", e.Code, e.Status)
+	if len(e.Texts) > 0 {
+		msg += ": " + strings.Join(e.Texts, "; ")
+	}
+	return msg
+}
+
+// WFSQuery is a stored query request
+type WFSQuery struct {
+	StoredQuery string
+	Location    url.Values // place, latlon or fmisid, and e.g. maxlocations
+	Parameters  []string
+	Start, End  time.Time
+	Timestep    time.Duration // Zero uses the stored query's default
+}
+
+// WFSLocation is the point or station a timeseries is for
+type WFSLocation struct {
+	Name      string
+	StationID string // fmisid, observations only
+	Lat, Lon  float64
+}
+
+// Timeseries holds the values of a query's parameters at one location
+type Timeseries struct {
+	Location WFSLocation
+	Times    []time.Time // Every time reported for any parameter, ascending
+
+	values map[string]map[time.Time]float64 // By lower case parameter
+	seen   map[time.Time]bool
+}
+
+// Value returns a parameter at t, or NaN if it is missing
+func (ts *Timeseries) Value(param string, t time.Time) float64 {
+	v, ok := ts.values[strings.ToLower(param)][t]
+	if !ok {
+		return math.NaN()
+	}
+	return v
+}
+
+// WFSClient runs FMI WFS stored queries. Responses are decoded while they
+// stream in, one timeseries at a time, so large queries stay cheap.
+type WFSClient struct {
+	URL  string
+	HTTP *HTTPClient
+}
+
+// newFMIClient returns a client for the configured FMI API
+func newFMIClient(cfg *config.Config, h *HTTPClient) *WFSClient {
+	return &WFSClient{URL: cfg.FMIAPIUrl, HTTP: h}
+}
+
+// Query runs q and returns one Timeseries per location, in response order.
+// An ExceptionReport is returned as a *WFSError.
+func (c *WFSClient) Query(ctx context.Context, q WFSQuery) ([]*Timeseries, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FMI url: %w", err)
+	}
+	params := url.Values{}
+	for k, v := range q.Location {
+		params[k] = v
+	}
+	params.Set("service", "WFS")
+	params.Set("version", "2.0.0")
+	params.Set("request", "getFeature")
+	params.Set("storedquery_id", q.StoredQuery)
+	params.Set("parameters", strings.Join(q.Parameters, ","))
+	if !q.Start.IsZero() {
+		params.Set("starttime", q.Start.UTC().Format(time.RFC3339))
+	}
+	if !q.End.IsZero() {
+		params.Set("endtime", q.End.UTC().Format(time.RFC3339))
+	}
+	if q.Timestep > 0 {
+		params.Set("timestep", strconv.Itoa(int(q.Timestep.Minutes())))
+	}
+	u.RawQuery = params.Encode()
+
+	var series []*Timeseries
+	err = c.HTTP.Stream(ctx, u.String(), nil, func(status int, body io.Reader) error {
+		var err error
+		series, err = decodeWFS(body, status, q.Parameters)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch FMI data: %w", err)
+	}
+	return series, nil
+}
+
+// wfsException is the XML form of an ExceptionReport
+type wfsException struct {
+	Exceptions []struct {
+		Code  string   `xml:"exceptionCode,attr"`
+		Texts []string `xml:"ExceptionText"`
+	} `xml:"Exception"`
+}
+
+// wfsMember is one PointTimeSeriesObservation: a single parameter at a
+// single location
+type wfsMember struct {
+	ObservedProperty struct {
+		Href string `xml:"href,attr"` // Names the parameter in its "param" query value
+	} `xml:"observedProperty"`
+	Feature struct {
+		Name      string `xml:"shape>Point>name"`
+		Pos       string `xml:"shape>Point>pos"` // "lat lon"
+		StationID string `xml:"sampledFeature>LocationCollection>member>Location>identifier"`
+	} `xml:"featureOfInterest>SF_SpatialSamplingFeature"`
+	Points []struct {
+		Time  string `xml:"MeasurementTVP>time"`
+		Value string `xml:"MeasurementTVP>value"`
+	} `xml:"result>MeasurementTimeseries>point"`
+}
+
+// decodeWFS reads a FeatureCollection or ExceptionReport from r. Each member
+// is matched to a parameter by its observedProperty; if that is missing,
+// by position, as FMI lists the requested parameters in order for each
+// location.
+func decodeWFS(r io.Reader, status int, params []string) ([]*Timeseries, error) {
+	statusErr := fmt.Errorf("FMI api returned status: %d", status)
+	dec := xml.NewDecoder(r)
+	var root string
+	var series []*Timeseries
+	byLocation := make(map[string]*Timeseries)
+	members := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if status != http.StatusOK {
+				return nil, statusErr
+			}
+			return nil, fmt.Errorf("failed to decode FMI XML: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if root == "" {
+			root = start.Name.Local
+			switch {
+			case root == "ExceptionReport":
+				var report wfsException
+				if err := dec.DecodeElement(&report, &start); err != nil {
+					return nil, fmt.Errorf("failed to decode FMI exception: %w", err)
+				}
+				wfsErr := &WFSError{Status: status}
+				for _, e := range report.Exceptions {
+					if wfsErr.Code == "" {
+						wfsErr.Code = e.Code
+					}
+					for _, t := range e.Texts {
+						wfsErr.Texts = append(wfsErr.Texts, strings.TrimSpace(t))
+					}
+				}
+				return nil, wfsErr
+			case status != http.StatusOK:
+				return nil, statusErr
+			case root != "FeatureCollection":
+				return nil, fmt.Errorf("unexpected FMI response <%s>", root)
+			}
+			continue
+		}
+		if start.Name.Local != "PointTimeSeriesObservation" {
+			continue
+		}
+
+		var m wfsMember
+		if err := dec.DecodeElement(&m, &start); err != nil {
+			return nil, fmt.Errorf("failed to decode FMI XML: %w", err)
+		}
+		param := memberParam(m.ObservedProperty.Href, params, members)
+		members++
+		if param == "" {
+			continue
+		}
+
+		// Members without a location belong to the previous one
+		key := m.Feature.StationID + "|" + m.Feature.Pos + "|" + m.Feature.Name
+		ts, ok := byLocation[key]
+		if key == "||" && len(series) > 0 {
+			ts, ok = series[len(series)-1], true
+		}
+		if !ok {
+			lat, lon := parsePos(m.Feature.Pos)
+			ts = &Timeseries{
+				Location: WFSLocation{Name: m.Feature.Name, StationID: m.Feature.StationID, Lat: lat, Lon: lon},
+				values:   make(map[string]map[time.Time]float64),
+				seen:     make(map[time.Time]bool),
+			}
+			byLocation[key] = ts
+			series = append(series, ts)
+		}
+		values := ts.values[param]
+		if values == nil {
+			values = make(map[time.Time]float64)
+			ts.values[param] = values
+		}
+		for _, p := range m.Points {
+			t, err := time.Parse(time.RFC3339, strings.TrimSpace(p.Time))
+			if err != nil {
+				continue
+			}
+			if !ts.seen[t] {
+				ts.seen[t] = true
+				ts.Times = append(ts.Times, t)
+			}
+			if v, err := strconv.ParseFloat(strings.TrimSpace(p.Value), 64); err == nil && !math.IsNaN(v) {
+				values[t] = v
+			}
+		}
+	}
+
+	if root == "" {
+		if status != http.StatusOK {
+			return nil, statusErr
+		}
+		return nil, errors.New("failed to decode FMI XML: no XML document")
+	}
+	for _, ts := range series {
+		sort.Slice(ts.Times, func(i, j int) bool { return ts.Times[i].Before(ts.Times[j]) })
+	}
+	return series, nil
+}
+
+// memberParam returns the lower case parameter of the n-th member, from the
+// observedProperty link if it has one, else from the requested order
+func memberParam(href string, params []string, n int) string {
+	if u, err := url.Parse(href); err == nil {
+		if p := u.Query().Get("param"); p != "" {
+			return strings.ToLower(p)
+		}
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return strings.ToLower(params[n%len(params)])
+}
+
+// parsePos parses a gml:pos "lat lon" pair, returning zeros if it is invalid
+func parsePos(pos string) (lat, lon float64) {
+	fields := strings.Fields(pos)
+	if len(fields) != 2 {
+		return 0, 0
+	}
+	lat, err1 := strconv.ParseFloat(fields[0], 64)
+	lon, err2 := strconv.ParseFloat(fields[1], 64)
+	if err1 != nil || err2 != nil {
+		return 0, 0
+	}
+	return lat, lon
+}
@@ -0,0 +1,165 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wfsCollection wraps members in a FeatureCollection
+func wfsCollection(members ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<wfs:FeatureCollection xmlns:wfs="http://www.opengis.net/wfs/2.0" xmlns:om="http://www.opengis.net/om/2.0" xmlns:omso="http://inspire.ec.europa.eu/schemas/omso/3.0" xmlns:sams="http://www.opengis.net/samplingSpatial/2.0" xmlns:wml2="http://www.opengis.net/waterml/2.0" xmlns:gml="http://www.opengis.net/gml/3.2">
+` + strings.Join(members, "") + `</wfs:FeatureCollection>`
+}
+
+// wfsPoint is a member with one value at 10:00 and no observedProperty
+// link, so its parameter is known only by position
+func wfsPoint(name, pos, value string) string {
+	return `  <wfs:member><omso:PointTimeSeriesObservation>
+    <om:featureOfInterest><sams:SF_SpatialSamplingFeature><sams:shape><gml:Point><gml:name>` + name + `</gml:name><gml:pos>` + pos + `</gml:pos></gml:Point></sams:shape></sams:SF_SpatialSamplingFeature></om:featureOfInterest>
+    <om:result><wml2:MeasurementTimeseries gml:id="mts-1">
+      <wml2:point><wml2:MeasurementTVP><wml2:time>2026-10-16T10:00:00Z</wml2:time><wml2:value>` + value + `</wml2:value></wml2:MeasurementTVP></wml2:point>
+    </wml2:MeasurementTimeseries></om:result>
+  </omso:PointTimeSeriesObservation></wfs:member>
+`
+}
+
+func TestWFSClient(t *testing.T) {
+	at := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	// Temperature and Precipitation1h at two locations
+	collection := wfsCollection(
+		wfsPoint("Espoo", "60.2 24.65", "5.5"), wfsPoint("Espoo", "60.2 24.65", "NaN"),
+		wfsPoint("Mökki", "61.05 28.19", "3.1"), wfsPoint("Mökki", "61.05 28.19", "0.4"),
+	)
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		gzip    bool
+		wantErr string
+		check   func(t *testing.T, series []*Timeseries)
+	}{
+		{
+			name:   "locations and positional parameters",
+			status: http.StatusOK,
+			body:   collection,
+			check: func(t *testing.T, series []*Timeseries) {
+				if len(series) != 2 {
+					t.Fatalf("got %d locations, want 2", len(series))
+				}
+				espoo, cabin := series[0], series[1]
+				if espoo.Location.Name != "Espoo" || espoo.Location.Lat != 60.2 || len(espoo.Times) != 1 {
+					t.Errorf("first location = %+v, times %v", espoo.Location, espoo.Times)
+				}
+				if v := espoo.Value("Temperature", at); v != 5.5 {
+					t.Errorf("Espoo temperature = %v, want 5.5", v)
+				}
+				if v := espoo.Value("Precipitation1h", at); !math.IsNaN(v) {
+					t.Errorf("Espoo NaN precipitation = %v, want missing", v)
+				}
+				if cabin.Value("temperature", at) != 3.1 || cabin.Value("PRECIPITATION1H", at) != 0.4 {
+					t.Errorf("cabin values wrong: %+v", cabin.values)
+				}
+			},
+		},
+		{
+			name:   "gzip",
+			status: http.StatusOK,
+			body:   collection,
+			gzip:   true,
+			check: func(t *testing.T, series []*Timeseries) {
+				if len(series) != 2 {
+					t.Errorf("got %d locations, want 2", len(series))
+				}
+			},
+		},
+		{
+			name:   "exception report",
+			status: http.StatusBadRequest,
+			body: `<?xml version="1.0" encoding="UTF-8"?>
+<ExceptionReport xmlns="http://www.opengis.net/ows/1.1" version="2.0.0">
+  <Exception exceptionCode="OperationParsingFailed">
+    <ExceptionText>Invalid parameter value for 'place'.</ExceptionText>
+    <ExceptionText>No locations found for the place with the requested language!</ExceptionText>
+  </Exception>
+</ExceptionReport>`,
+			wantErr: "OperationParsingFailed (status 400): Invalid parameter value for 'place'.; No locations found",
+		},
+		{
+			name:    "server error without report",
+			status:  http.StatusInternalServerError,
+			body:    "<html><body>Internal error</body>",
+			wantErr: "status: 500",
+		},
+		{
+			name:    "unexpected document",
+			status:  http.StatusOK,
+			body:    `<html><body>maintenance</body></html>`,
+			wantErr: "unexpected FMI response <html>",
+		},
+		{
+			name:    "empty body",
+			status:  http.StatusOK,
+			wantErr: "no XML document",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query url.Values
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+				body := []byte(tt.body)
+				if tt.gzip {
+					var buf bytes.Buffer
+					gz := gzip.NewWriter(&buf)
+					gz.Write(body)
+					gz.Close()
+					body = buf.Bytes()
+					w.Header().Set("Content-Encoding", "gzip")
+				}
+				w.WriteHeader(tt.status)
+				w.Write(body)
+			}))
+			t.Cleanup(srv.Close)
+			client := &WFSClient{URL: srv.URL + "/wfs", HTTP: NewHTTPClient(testConfig(nil))}
+
+			series, err := client.Query(context.Background(), WFSQuery{
+				StoredQuery: ForecastQuery,
+				Location:    url.Values{"latlon": {"60.2,24.65"}},
+				Parameters:  []string{"Temperature", "Precipitation1h"},
+				Start:       at,
+				Timestep:    time.Hour,
+			})
+			if got := query.Get("parameters"); got != "Temperature,Precipitation1h" || query.Get("timestep") != "60" || query.Get("latlon") != "60.2,24.65" {
+				t.Errorf("query = %v", query)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				var wfsErr *WFSError
+				wantReport := strings.Contains(tt.body, "ExceptionReport")
+				if errors.As(err, &wfsErr) != wantReport {
+					t.Errorf("errors.As(%v, *WFSError) = %v, want %v", err, !wantReport, wantReport)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+			tt.check(t, series)
+		})
+	}
+}
@@ -1,29 +1,87 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"rasp_info/config"
 	"rasp_info/fetcher"
+	"rasp_info/mockapi"
+	"rasp_info/scheduler"
 	"rasp_info/store"
+	"reflect"
 	"runtime"
+	"strconv"
+	"syscall"
 	"time"
 )
 
+// shutdownTimeout bounds how long we wait for in-flight fetches and HTTP
+// requests when the service is stopped
+const shutdownTimeout = 10 * time.Second
+
+// snapshotInterval is how often changed data is persisted to disk
+const snapshotInterval = time.Minute
+
+// configPollInterval is how often config.json is checked for changes
+const configPollInterval = 2 * time.Second
+
 var startTime = time.Now()
 
 func main() {
 	// Parse flags
 	lookupCode := flag.String("lookup", "", "Lookup HSL stop by short code (e.g. E2185)")
+	configPath := flag.String("config", config.DefaultPath, "Path to config file")
+	printConfig := flag.Bool("print-config", false, "Print the effective config and where each value came from, then exit")
+	recordDir := flag.String("record", "", "Save every API response as a fixture file in this directory")
+	replayDir := flag.String("replay", "", "Answer API requests from fixture files in this directory instead of the network")
+	mockScenario := flag.String("mock", "", "Serve synthetic API data in-process with these scenarios (e.g. normal or empty,nan) instead of calling the real APIs")
+	loader := &config.Loader{}
+	loader.BindFlags(flag.CommandLine)
 	flag.Parse()
+	loader.Path = *configPath
 
-	cfg := config.Load()
+	if *mockScenario != "" {
+		base, err := startMock(*mockScenario)
+		if err != nil {
+			log.Fatalf("Mock error: %v", err)
+		}
+		log.Printf("Using mock APIs at %s (scenarios: %s)", base, *mockScenario)
+		// Mock URLs override config like command line flags would
+		for name, url := range mockapi.URLs(base) {
+			if err := flag.Set(config.FlagName(name), url); err != nil {
+				log.Fatalf("Mock error: cannot point %s at the mock APIs: %v", name, err)
+			}
+		}
+	}
+
+	cfgMgr, err := config.NewManager(loader)
+	if err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+	cfg := cfgMgr.Get() // Startup values; fetchers read cfgMgr on every fetch
+
+	if *printConfig {
+		if err := config.Print(os.Stdout, cfg, cfgMgr.Origins()); err != nil {
+			log.Fatalf("Error printing config: %v", err)
+		}
+		return
+	}
 	st := store.New()
+	st.SetWindowFunc(func(prices []store.PriceInfo, now time.Time) []store.PriceWindow {
+		return fetcher.ApplianceWindows(prices, cfgMgr.Get().Appliances, now)
+	})
+
+	// Cancelled on SIGINT (Ctrl+C) or SIGTERM (systemd stop)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Setup Log Capture
 	logWriter := &LogWriter{
@@ -33,20 +91,32 @@ func main() {
 	log.SetOutput(logWriter)
 
 	// Initialize Fetchers
+	httpClient := fetcher.NewHTTPClient(cfgMgr) // Shared by all fetchers
+	switch {
+	case *recordDir != "":
+		log.Printf("Recording API responses to %s", *recordDir)
+		httpClient = fetcher.NewReplayClient(*recordDir, fetcher.ModeRecord, httpClient)
+	case *replayDir != "":
+		log.Printf("Replaying API responses from %s", *replayDir)
+		httpClient = fetcher.NewReplayClient(*replayDir, fetcher.ModeReplay, httpClient)
+	}
+	innerHSL := &fetcher.HSLFetcher{Config: cfgMgr, Store: st, HTTP: httpClient}
+	stopCache := fetcher.NewStopCache(cfg.StopCachePath, cfg.StopCacheTTL.Duration, innerHSL.LookupStop)
+	innerHSL.Stops = stopCache
 	hslFetcher := &fetcher.LoggingFetcher{
-		Fetcher: &fetcher.HSLFetcher{Config: cfg, Store: st},
-		Store:   st,
-		Name:    "HSL",
+		Fetcher:  innerHSL,
+		Store:    st,
+		Name:     "HSL",
+		Sections: []string{store.SectionTransport, store.SectionAlerts},
 	}
 
 	// Handle Lookup Mode
 	if *lookupCode != "" {
 		if cfg.HSLKey == "" {
-			log.Fatal("HSL API key is missing. Please configure it in config.json or secrets.txt")
+			log.Fatal("HSL API key is missing. Please configure it in config.json, secrets.txt or INFOBOARD_HSL_KEY")
 		}
-		// Directly construct an HSLFetcher for lookup so we can access LookupStop
-		innerHSL := &fetcher.HSLFetcher{Config: cfg, Store: st}
-		id, err := innerHSL.LookupStop(*lookupCode)
+		// Bypass the cache so lookup always reflects the API
+		id, err := innerHSL.LookupStop(ctx, *lookupCode)
 		if err != nil {
 			log.Fatalf("Error looking up stop: %v", err)
 		}
@@ -55,31 +125,114 @@ func main() {
 	}
 
 	fmiFetcher := &fetcher.LoggingFetcher{
-		Fetcher: &fetcher.FMIFetcher{Config: cfg, Store: st},
-		Store:   st,
-		Name:    "FMI",
+		Fetcher:  &fetcher.FMIFetcher{Config: cfgMgr, Store: st, HTTP: httpClient},
+		Store:    st,
+		Name:     "FMI",
+		Sections: []string{store.SectionWeather},
+	}
+	warningsFetcher := &fetcher.LoggingFetcher{
+		Fetcher:  &fetcher.WarningsFetcher{Config: cfgMgr, Store: st, HTTP: httpClient},
+		Store:    st,
+		Name:     "Warnings",
+		Sections: []string{store.SectionWarnings},
+	}
+	astroFetcher := &fetcher.LoggingFetcher{
+		Fetcher:  &fetcher.AstronomyFetcher{Config: cfgMgr, Store: st},
+		Store:    st,
+		Name:     "Astronomy",
+		Sections: []string{store.SectionAstronomy},
 	}
 	elecFetcher := &fetcher.LoggingFetcher{
-		Fetcher: &fetcher.ElectricityFetcher{Config: cfg, Store: st},
-		Store:   st,
-		Name:    "Electricity",
+		Fetcher:  &fetcher.ElectricityFetcher{Config: cfgMgr, Store: st, HTTP: httpClient},
+		Store:    st,
+		Name:     "Electricity",
+		Sections: []string{store.SectionElectricity},
+	}
+
+	// Restore the last known data so the kiosk has something to show
+	// before the first fetches complete (or if the network is down)
+	if cfg.SnapshotPath != "" {
+		if err := st.LoadSnapshot(cfg.SnapshotPath); err == nil {
+			log.Printf("Restored snapshot from %s", cfg.SnapshotPath)
+		} else if !os.IsNotExist(err) {
+			log.Printf("Error loading snapshot: %v", err)
+		}
+		go st.RunSnapshots(ctx, cfg.SnapshotPath, snapshotInterval)
+	}
+
+	st.SetInterval(store.SectionTransport, cfg.TransportInterval.Duration)
+	st.SetInterval(store.SectionAlerts, cfg.TransportInterval.Duration)
+	st.SetInterval(store.SectionWeather, cfg.WeatherInterval.Duration)
+	st.SetInterval(store.SectionWarnings, cfg.WarningsInterval.Duration)
+	st.SetInterval(store.SectionAstronomy, 24*time.Hour)
+	st.SetInterval(store.SectionElectricity, cfg.ElectricityInterval.Duration)
+	st.SetInterval(store.SectionJourneys, cfg.PlannerInterval.Duration)
+	st.SetInterval(store.SectionBikes, cfg.BikeInterval.Duration)
+
+	plannerFetcher := &fetcher.LoggingFetcher{
+		Fetcher:  &fetcher.PlannerFetcher{Config: cfgMgr, Store: st, HTTP: httpClient},
+		Store:    st,
+		Name:     "Planner",
+		Sections: []string{store.SectionJourneys},
+	}
+
+	bikeFetcher := &fetcher.LoggingFetcher{
+		Fetcher:  &fetcher.CityBikeFetcher{Config: cfgMgr, Store: st, HTTP: httpClient},
+		Store:    st,
+		Name:     "CityBike",
+		Sections: []string{store.SectionBikes},
 	}
 
-	// Start background jobs
-	go runTicker(cfg.TransportInterval, hslFetcher)
-	go runTicker(cfg.WeatherInterval, fmiFetcher)
-	go runTicker(cfg.ElectricityInterval, elecFetcher)
+	// Warm the stop cache; the HSL fetcher resolves on demand if this is slow
+	go stopCache.ResolveAll(ctx, cfg.StopCodes())
+
+	// Start background jobs (each source is fetched immediately, then on its interval)
+	sched := scheduler.New()
+	sched.Add("HSL", hslFetcher, cfg.TransportTiming())
+	sched.Add("FMI", fmiFetcher, cfg.WeatherTiming())
+	sched.Add("Warnings", warningsFetcher, cfg.WarningsTiming())
+	sched.Add("Astronomy", astroFetcher, scheduler.Daily(time.Minute)) // Just after midnight
+	sched.Add("Electricity", elecFetcher, cfg.ElectricityTiming())
+	sched.Add("Planner", plannerFetcher, cfg.PlannerTiming())
+	sched.Add("CityBike", bikeFetcher, cfg.BikeTiming())
+	sched.Start(ctx)
 
-	// Initial fetch
-	go hslFetcher.Fetch()
-	go fmiFetcher.Fetch()
-	go elecFetcher.Fetch()
+	// Config reloads: on file change or SIGHUP
+	cfgMgr.OnChange(func(prev, next *config.Config) {
+		stopCache.SetTTL(next.StopCacheTTL.Duration)
+		go stopCache.ResolveAll(ctx, next.StopCodes())
+		applyConfigChange(prev, next, sched, st)
+	})
+	go cfgMgr.Watch(ctx, configPollInterval)
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				log.Println("Config: SIGHUP received, reloading")
+				if err := cfgMgr.Reload(); err != nil {
+					log.Printf("Config: reload failed, keeping previous config: %v", err)
+				} else {
+					log.Println("Config: reloaded")
+				}
+			}
+		}
+	}()
 
 	// Device Stats Ticker
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
 		defer ticker.Stop()
-		for range ticker.C {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
 			var m runtime.MemStats
 			runtime.ReadMemStats(&m)
 			st.UpdateDeviceInfo(store.DeviceInfo{
@@ -101,20 +254,43 @@ func main() {
 		}
 	})
 
-	http.HandleFunc("/api/debug/status", func(w http.ResponseWriter, r *http.Request) {
+	// Cheapest time to run something for ?hours=N (multiples of 0.25)
+	http.HandleFunc("/api/electricity/windows", func(w http.ResponseWriter, r *http.Request) {
+		hours, err := strconv.ParseFloat(r.URL.Query().Get("hours"), 64)
+		if err != nil {
+			http.Error(w, "hours must be a number, e.g. ?hours=3", http.StatusBadRequest)
+			return
+		}
+		d := time.Duration(hours * float64(time.Hour))
+		if err := config.CheckWindowDuration(d); err != nil {
+			http.Error(w, "hours "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		window, ok := fetcher.CheapestWindow(st.Get().Electricity.Prices, d, time.Now())
+		if !ok {
+			http.Error(w, fmt.Sprintf("prices are not known %s ahead", d), http.StatusNotFound)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
-		// Create a masked config copy
-		maskedCfg := *cfg
-		if maskedCfg.HSLKey != "" {
-			maskedCfg.HSLKey = "***MASKED***"
+		if err := json.NewEncoder(w).Encode(window); err != nil {
+			log.Printf("Error encoding price window: %v", err)
 		}
+	})
+
+	http.HandleFunc("/api/events", serveEvents(ctx, st))
 
+	http.HandleFunc("/api/debug/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 		resp := struct {
-			Config config.Config `json:"config"`
-			Store  store.Data    `json:"store"`
+			Config  config.Config            `json:"config"`
+			Origins config.Origins           `json:"config_origins"`
+			StopIDs []fetcher.StopResolution `json:"stop_ids"`
+			Store   store.Data               `json:"store"`
 		}{
-			Config: maskedCfg,
-			Store:  st.Get(),
+			Config:  cfgMgr.Get().Masked(), // Secrets masked
+			Origins: cfgMgr.Origins(),
+			StopIDs: stopCache.Entries(),
+			Store:   st.Get(),
 		}
 
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -138,6 +314,13 @@ func main() {
 		}
 	})
 
+	http.HandleFunc("/api/debug/scheduler", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(sched.Health()); err != nil {
+			log.Printf("Error encoding scheduler health: %v", err)
+		}
+	})
+
 	http.HandleFunc("/api/debug/device", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		data := st.GetDebugData()
@@ -150,20 +333,93 @@ func main() {
 	fs := http.FileServer(http.Dir("./static"))
 	http.Handle("/", fs)
 
-	log.Printf("Server starting on %s", cfg.Port)
-	if err := http.ListenAndServe(cfg.Port, nil); err != nil {
+	srv := &http.Server{Addr: cfg.Port}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on %s", cfg.Port)
+		serverErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
 		log.Fatalf("Server failed: %v", err)
+	case <-ctx.Done():
+	}
+
+	// Graceful shutdown: stop fetching, drain in-flight work, close server
+	log.Println("Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := sched.Stop(shutdownCtx); err != nil {
+		log.Printf("Scheduler did not stop cleanly: %v", err)
 	}
+	if cfg.SnapshotPath != "" {
+		st.SaveIfChanged(cfg.SnapshotPath)
+	}
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
+	log.Println("Shutdown complete")
 }
 
-func runTicker(interval time.Duration, f fetcher.Fetcher) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for range ticker.C {
-		if err := f.Fetch(); err != nil {
-			log.Printf("Error fetching data: %v", err)
-		}
+// applyConfigChange pushes a reloaded config to the running components.
+// Sources whose inputs changed are refetched right away.
+func applyConfigChange(prev, next *config.Config, sched *scheduler.Scheduler, st *store.Store) {
+	sched.SetTiming("HSL", next.TransportTiming())
+	sched.SetTiming("FMI", next.WeatherTiming())
+	sched.SetTiming("Warnings", next.WarningsTiming())
+	sched.SetTiming("Electricity", next.ElectricityTiming())
+	sched.SetTiming("Planner", next.PlannerTiming())
+	sched.SetTiming("CityBike", next.BikeTiming())
+	st.SetInterval(store.SectionTransport, next.TransportInterval.Duration)
+	st.SetInterval(store.SectionAlerts, next.TransportInterval.Duration)
+	st.SetInterval(store.SectionWeather, next.WeatherInterval.Duration)
+	st.SetInterval(store.SectionWarnings, next.WarningsInterval.Duration)
+	st.SetInterval(store.SectionElectricity, next.ElectricityInterval.Duration)
+	st.SetInterval(store.SectionJourneys, next.PlannerInterval.Duration)
+	st.SetInterval(store.SectionBikes, next.BikeInterval.Duration)
+
+	if !reflect.DeepEqual(prev.BusStops, next.BusStops) || prev.HSLKey != next.HSLKey || prev.HSLAPIUrl != next.HSLAPIUrl {
+		sched.Trigger("HSL")
+	}
+	if !reflect.DeepEqual(prev.WeatherPlaces(), next.WeatherPlaces()) || prev.FMIAPIUrl != next.FMIAPIUrl {
+		sched.Trigger("FMI")
+	}
+	if !reflect.DeepEqual(prev.WarningRegions, next.WarningRegions) || !reflect.DeepEqual(prev.WeatherPlaces(), next.WeatherPlaces()) || prev.WarningsAPIUrl != next.WarningsAPIUrl {
+		sched.Trigger("Warnings")
+	}
+	if !reflect.DeepEqual(prev.WeatherPlaces()[0], next.WeatherPlaces()[0]) {
+		sched.Trigger("Astronomy")
+	}
+	// Appliance windows are computed on read; the refetch pushes them to clients
+	if prev.SpotAPIUrl != next.SpotAPIUrl || !reflect.DeepEqual(prev.Appliances, next.Appliances) {
+		sched.Trigger("Electricity")
+	}
+	if !reflect.DeepEqual(prev.Trips, next.Trips) || prev.HSLKey != next.HSLKey || prev.HSLAPIUrl != next.HSLAPIUrl {
+		sched.Trigger("Planner")
+	}
+	if !reflect.DeepEqual(prev.BikeStations, next.BikeStations) || prev.BikeSeasonStart != next.BikeSeasonStart || prev.BikeSeasonEnd != next.BikeSeasonEnd {
+		sched.Trigger("CityBike")
+	}
+
+	if prev.Port != next.Port || prev.SnapshotPath != next.SnapshotPath || prev.StopCachePath != next.StopCachePath {
+		log.Println("Config: port, snapshot_path and stop_cache_path changes take effect after a restart")
+	}
+}
+
+// startMock serves the mock APIs on a free local port and returns its URL
+func startMock(scenarios string) (string, error) {
+	srv, err := mockapi.New(scenarios, 3*time.Second, 0)
+	if err != nil {
+		return "", err
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
 	}
+	go http.Serve(ln, srv.Handler())
+	return "http://" + ln.Addr().String(), nil
 }
 
 // LogWriter captures logs to store and stdout
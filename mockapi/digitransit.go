@@ -0,0 +1,292 @@
+package mockapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// Top-level aliased fields, e.g. "stop0: stop(id: $v0)"
+	fieldPattern = regexp.MustCompile(`(\w+):\s*(stop|plan|vehicleRentalStation)\(([^)]*)\)`)
+	// Field arguments bound to variables, e.g. "numberOfDepartures: $v2"
+	argPattern = regexp.MustCompile(`(\w+):\s*\$(\w+)`)
+)
+
+type mockRoute struct {
+	name, mode, headsign string
+}
+
+var mockRoutes = []mockRoute{
+	{"110", "BUS", "Kamppi"},
+	{"114", "BUS", "Matinkylä"},
+	{"M1", "SUBWAY", "Vuosaari"},
+	{"550", "BUS", "Itäkeskus"},
+	{"15", "TRAM", "Ruoholahti"},
+	{"E", "RAIL", "Kauklahti"},
+}
+
+// handleGraphQL answers the stop, plan and vehicleRentalStation fields the
+// dashboard asks for. Other fields are ignored.
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	data := make(map[string]any)
+	for _, m := range fieldPattern.FindAllStringSubmatchIndex(req.Query, -1) {
+		alias, field := req.Query[m[2]:m[3]], req.Query[m[4]:m[5]]
+		// Arguments of nested fields, e.g. stoptimesWithoutPatterns'
+		// numberOfDepartures, count too; the field's own win on a clash
+		args := make(map[string]any)
+		for _, text := range []string{selection(req.Query, m[1]), req.Query[m[6]:m[7]]} {
+			for _, a := range argPattern.FindAllStringSubmatch(text, -1) {
+				args[a[1]] = req.Variables[a[2]]
+			}
+		}
+		switch field {
+		case "stop":
+			data[alias] = s.mockStop(args)
+		case "plan":
+			data[alias] = s.mockPlan(args)
+		case "vehicleRentalStation":
+			data[alias] = s.mockBikeStation(args)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+// selection returns the braced selection set following offset in query, or
+// "" if the field has none
+func selection(query string, offset int) string {
+	start := strings.IndexByte(query[offset:], '{')
+	if start < 0 || strings.TrimSpace(query[offset:offset+start]) != "" {
+		return ""
+	}
+	start += offset
+	depth := 0
+	for i := start; i < len(query); i++ {
+		switch query[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return query[start : i+1]
+			}
+		}
+	}
+	return query[start:]
+}
+
+func (s *Server) mockStop(args map[string]any) any {
+	id, _ := args["id"].(string)
+	if !strings.HasPrefix(id, "HSL:") {
+		return nil // Unknown stop
+	}
+	count := intArg(args, "numberOfDepartures", 5)
+	now := time.Now()
+	if start := intArg(args, "startTime", 0); start > 0 {
+		now = time.Unix(int64(start), 0)
+	}
+
+	serviceDay := s.serviceDay(now)
+	var stoptimes []map[string]any
+	var routes []map[string]any
+	if !s.has("empty") {
+		next := now.Add(time.Duration(1+rand.IntN(3)) * time.Minute)
+		for i := 0; i < count; i++ {
+			route := mockRoutes[i%len(mockRoutes)]
+			scheduled := int(next.Unix() - serviceDay)
+			delay := rand.IntN(240) - 60
+			realtime := !s.has("nan") && i%4 != 3
+			state := "UPDATED"
+			if !realtime {
+				delay, state = 0, "SCHEDULED"
+			} else if i == 5 {
+				state = "CANCELED"
+			}
+			stoptimes = append(stoptimes, map[string]any{
+				"scheduledDeparture": scheduled,
+				"realtimeDeparture":  scheduled + delay,
+				"departureDelay":     delay,
+				"realtime":           realtime,
+				"realtimeState":      state,
+				"pickupType":         "SCHEDULED",
+				"dropoffType":        "SCHEDULED",
+				"serviceDay":         serviceDay,
+				"headsign":           route.headsign,
+				"stop":               map[string]any{"code": stopCode(id), "platformCode": fmt.Sprint(1 + i%2)},
+				"trip": map[string]any{"route": map[string]any{
+					"shortName": route.name,
+					"mode":      route.mode,
+				}},
+			})
+			next = next.Add(time.Duration(2+rand.IntN(6)) * time.Minute)
+		}
+		routes = append(routes, map[string]any{
+			"shortName": mockRoutes[0].name,
+			"alerts":    []any{mockAlert(now)},
+		})
+	}
+
+	return map[string]any{
+		"name":                     "Mock " + stopCode(id),
+		"code":                     stopCode(id),
+		"stoptimesWithoutPatterns": stoptimes,
+		"alerts":                   []any{},
+		"routes":                   routes,
+	}
+}
+
+// serviceDay is the GTFS service day start: noon minus 12 hours, which is
+// not midnight on days the clocks change
+func (s *Server) serviceDay(now time.Time) int64 {
+	noon := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, now.Location())
+	day := noon.Add(-12 * time.Hour).Unix()
+	if s.has("dst") {
+		day -= 3600
+	}
+	return day
+}
+
+func mockAlert(now time.Time) map[string]any {
+	return map[string]any{
+		"id":                   "mock-alert-1",
+		"alertSeverityLevel":   "WARNING",
+		"alertUrl":             "https://www.hsl.fi/",
+		"effectiveStartDate":   now.Add(-time.Hour).Unix(),
+		"effectiveEndDate":     now.Add(6 * time.Hour).Unix(),
+		"alertHeaderText":      "Poikkeusreitti",
+		"alertDescriptionText": "Linja 110 kulkee poikkeusreittiä katutöiden vuoksi.",
+		"alertHeaderTextTranslations": []map[string]string{
+			{"text": "Poikkeusreitti", "language": "fi"},
+			{"text": "Diversion", "language": "en"},
+		},
+		"alertDescriptionTextTranslations": []map[string]string{
+			{"text": "Linja 110 kulkee poikkeusreittiä katutöiden vuoksi.", "language": "fi"},
+			{"text": "Route 110 is diverted due to roadworks.", "language": "en"},
+		},
+	}
+}
+
+func (s *Server) mockPlan(args map[string]any) any {
+	if s.has("empty") {
+		return map[string]any{"itineraries": []any{}}
+	}
+	count := intArg(args, "numItineraries", 3)
+	arriveBy, _ := args["arriveBy"].(bool)
+	start := time.Now().Add(5 * time.Minute)
+	if arriveBy {
+		date, _ := args["date"].(string)
+		clock, _ := args["time"].(string)
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", date+" "+clock, time.Local); err == nil {
+			start = t.Add(-35 * time.Minute)
+		}
+	}
+
+	var itineraries []map[string]any
+	for i := 0; i < count; i++ {
+		route := mockRoutes[i%len(mockRoutes)]
+		legStart := start.Add(time.Duration(i*10) * time.Minute)
+		if arriveBy {
+			legStart = start.Add(-time.Duration(i*10) * time.Minute)
+		}
+		board := legStart.Add(4 * time.Minute)
+		alight := board.Add(22 * time.Minute)
+		end := alight.Add(6 * time.Minute)
+		itineraries = append(itineraries, map[string]any{
+			"startTime":    legStart.UnixMilli(),
+			"endTime":      end.UnixMilli(),
+			"duration":     int(end.Sub(legStart).Seconds()),
+			"walkDistance": 650.0,
+			"legs": []map[string]any{
+				mockLeg("WALK", legStart, board, "Origin", "Mock stop", nil, 300),
+				mockLeg(route.mode, board, alight, "Mock stop", "Mock terminus", &route, 9000),
+				mockLeg("WALK", alight, end, "Mock terminus", "Destination", nil, 350),
+			},
+		})
+	}
+	return map[string]any{"itineraries": itineraries}
+}
+
+func mockLeg(mode string, start, end time.Time, from, to string, route *mockRoute, distance float64) map[string]any {
+	leg := map[string]any{
+		"mode":       mode,
+		"startTime":  start.UnixMilli(),
+		"endTime":    end.UnixMilli(),
+		"realTime":   route != nil,
+		"distance":   distance,
+		"transitLeg": route != nil,
+		"from":       map[string]string{"name": from},
+		"to":         map[string]string{"name": to},
+	}
+	if route != nil {
+		leg["route"] = map[string]string{"shortName": route.name}
+		leg["trip"] = map[string]string{"tripHeadsign": route.headsign}
+	}
+	return leg
+}
+
+func (s *Server) mockBikeStation(args map[string]any) any {
+	id, _ := args["id"].(string)
+	if id == "" {
+		return nil
+	}
+	bikes := 0
+	if !s.has("empty") {
+		bikes = rand.IntN(15)
+	}
+	return map[string]any{
+		"stationId":         id,
+		"name":              "Mock station " + id,
+		"operative":         true,
+		"realtime":          true,
+		"availableVehicles": map[string]int{"total": bikes},
+		"availableSpaces":   map[string]int{"total": 20 - bikes},
+	}
+}
+
+// handleGeocoding resolves any short code to a made-up GTFS id
+func (s *Server) handleGeocoding(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("text")
+	var features []any
+	if code != "" && !s.has("empty") {
+		var n uint32
+		for _, c := range code {
+			n = n*31 + uint32(c)
+		}
+		gid := fmt.Sprintf("gtfshsl:stop:GTFS:HSL:%07d#%s", 1000000+n%9000000, code)
+		features = append(features, map[string]any{"properties": map[string]string{"gid": gid}})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"features": features})
+}
+
+// stopCode derives a short code like E1234 from a GTFS id
+func stopCode(id string) string {
+	digits := strings.TrimPrefix(id, "HSL:")
+	if len(digits) > 4 {
+		digits = digits[len(digits)-4:]
+	}
+	return "E" + digits
+}
+
+// intArg reads a numeric variable; JSON numbers decode as float64
+func intArg(args map[string]any, name string, def int) int {
+	if v, ok := args[name].(float64); ok {
+		return int(v)
+	}
+	return def
+}
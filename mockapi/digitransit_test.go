@@ -0,0 +1,77 @@
+package mockapi_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"rasp_info/config"
+	"rasp_info/fetcher"
+	"rasp_info/mockapi"
+	"rasp_info/store"
+	"testing"
+	"time"
+)
+
+// TestGraphQLStopArguments sends the HSL fetcher's real query to the mock and
+// checks that the nested stoptimes arguments are honoured
+func TestGraphQLStopArguments(t *testing.T) {
+	srv, err := mockapi.New("normal", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	tests := []struct {
+		name          string
+		stop          config.BusStop
+		wantCount     int
+		wantCancelled bool
+		startAfter    time.Duration // No departure before now plus this
+	}{
+		{name: "default count", stop: config.BusStop{ID: "HSL:1"}, wantCount: config.DefaultDepartures},
+		{name: "per-stop count with a cancelled departure", stop: config.BusStop{ID: "HSL:1", Departures: 8}, wantCount: 8, wantCancelled: true},
+		{name: "over-fetch for a route filter", stop: config.BusStop{ID: "HSL:1", Departures: 2, Routes: []string{"110"}}, wantCount: 2},
+		{
+			name:          "walking time moves the start",
+			stop:          config.BusStop{ID: "HSL:1", Departures: 6, WalkingTime: config.Duration{Duration: 20 * time.Minute}},
+			wantCount:     6,
+			wantCancelled: true,
+			startAfter:    20 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.HSLKey = "test-key"
+			cfg.HSLAPIUrl = mockapi.URLs(ts.URL)["hsl_api_url"]
+			cfg.BusStops = []config.BusStop{tt.stop}
+			cfgMgr := config.Static(cfg)
+			st := store.New()
+			f := &fetcher.HSLFetcher{Config: cfgMgr, Store: st, HTTP: fetcher.NewHTTPClient(cfgMgr)}
+
+			start := time.Now()
+			if err := f.Fetch(context.Background()); err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			stops := st.Get().Transport.Stops
+			if len(stops) != 1 {
+				t.Fatalf("got %d stops, want 1", len(stops))
+			}
+			deps := stops[0].Departures
+			if len(deps) != tt.wantCount {
+				t.Errorf("got %d departures, want %d", len(deps), tt.wantCount)
+			}
+			cancelled := false
+			for _, d := range deps {
+				if d.ScheduledTime.Before(start.Add(tt.startAfter).Truncate(time.Second)) {
+					t.Errorf("departure at %s is before the start time", d.ScheduledTime)
+				}
+				cancelled = cancelled || d.Cancelled
+			}
+			if cancelled != tt.wantCancelled {
+				t.Errorf("cancelled departure present = %v, want %v", cancelled, tt.wantCancelled)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package mockapi
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleFMI serves WFS timevaluepair responses for any requested parameters.
+// Forecast queries cover starttime..endtime; observation queries the last
+// hours up to now.
+func (s *Server) handleFMI(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := q.Get("storedquery_id")
+	if !strings.HasSuffix(query, "::timevaluepair") {
+		writeFMIException(w, fmt.Sprintf("Stored query %q is not supported by the mock.", query))
+		return
+	}
+
+	kind := "forecast"
+	step := time.Hour
+	if m, err := strconv.Atoi(q.Get("timestep")); err == nil && m > 0 {
+		step = time.Duration(m) * time.Minute
+	}
+	now := time.Now().UTC()
+	start := now.Truncate(step)
+	end := start.Add(24 * time.Hour)
+	if strings.Contains(query, "observations") {
+		kind = "observation"
+		step = 10 * time.Minute
+		end = now.Truncate(step)
+		start = end.Add(-2 * time.Hour)
+	}
+	if t, err := time.Parse(time.RFC3339, q.Get("starttime")); err == nil {
+		start = t.UTC().Truncate(step)
+	}
+	if t, err := time.Parse(time.RFC3339, q.Get("endtime")); err == nil {
+		end = t.UTC()
+	}
+
+	params := strings.Split(q.Get("parameters"), ",")
+	if q.Get("parameters") == "" {
+		params = []string{"temperature"}
+	}
+	location, fmisid := q.Get("place"), q.Get("fmisid")
+	if fmisid == "" {
+		fmisid = "100971"
+	}
+	pos := "60.17523 24.94459"
+	if latlon := q.Get("latlon"); latlon != "" {
+		pos = strings.Replace(latlon, ",", " ", 1)
+		if location == "" {
+			location = "Mock point " + latlon
+		}
+	}
+	if location == "" {
+		location = "Mock station " + fmisid
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<wfs:FeatureCollection xmlns:wfs="http://www.opengis.net/wfs/2.0" xmlns:om="http://www.opengis.net/om/2.0" xmlns:omso="http://inspire.ec.europa.eu/schemas/omso/3.0" xmlns:wml2="http://www.opengis.net/waterml/2.0" xmlns:gml="http://www.opengis.net/gml/3.2" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:sam="http://www.opengis.net/sampling/2.0" xmlns:sams="http://www.opengis.net/samplingSpatial/2.0" xmlns:target="http://xml.fmi.fi/namespace/om/atmosphericfeatures/1.1">
+`)
+	if !s.has("empty") {
+		for i, param := range params {
+			id := fmt.Sprintf("obs-obs-1-%d", i+1)
+			fmt.Fprintf(&b, `  <wfs:member>
+    <omso:PointTimeSeriesObservation gml:id="%s">
+      <om:observedProperty xlink:href="https://opendata.fmi.fi/meta?observableProperty=%s&amp;param=%s&amp;language=eng"/>
+      <om:featureOfInterest>
+        <sams:SF_SpatialSamplingFeature>
+          <sam:sampledFeature>
+            <target:LocationCollection>
+              <target:member>
+                <target:Location>
+                  <gml:identifier codeSpace="http://xml.fmi.fi/namespace/stationcode/fmisid">%s</gml:identifier>
+                  <gml:name codeSpace="http://xml.fmi.fi/namespace/locationcode/name">%s</gml:name>
+                </target:Location>
+              </target:member>
+            </target:LocationCollection>
+          </sam:sampledFeature>
+          <sams:shape>
+            <gml:Point gml:id="point-%d">
+              <gml:name>%s</gml:name>
+              <gml:pos>%s</gml:pos>
+            </gml:Point>
+          </sams:shape>
+        </sams:SF_SpatialSamplingFeature>
+      </om:featureOfInterest>
+      <om:result>
+        <wml2:MeasurementTimeseries gml:id="%s-%s">
+`, id, kind, param, fmisid, location, i+1, location, pos, id, param)
+			for t, n := start, 0; !t.After(end); t, n = t.Add(step), n+1 {
+				value := "NaN"
+				if !s.has("nan") || n%3 != 2 {
+					value = strconv.FormatFloat(mockWeather(param, t), 'f', 1, 64)
+				}
+				fmt.Fprintf(&b, "          <wml2:point><wml2:MeasurementTVP><wml2:time>%s</wml2:time><wml2:value>%s</wml2:value></wml2:MeasurementTVP></wml2:point>\n",
+					t.Format(time.RFC3339), value)
+			}
+			b.WriteString("        </wml2:MeasurementTimeseries>\n      </om:result>\n    </omso:PointTimeSeriesObservation>\n  </wfs:member>\n")
+		}
+	}
+	b.WriteString("</wfs:FeatureCollection>\n")
+
+	w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+	w.Write([]byte(b.String()))
+}
+
+// mockWeather returns a plausible value for an FMI parameter, following a
+// daily cycle
+func mockWeather(param string, t time.Time) float64 {
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	cycle := math.Sin((hour - 9) / 24 * 2 * math.Pi) // Peaks mid-afternoon local time
+	switch strings.ToLower(param) {
+	case "temperature", "t2m":
+		return 6 + 4*cycle
+	case "precipitation1h", "r_1h":
+		if t.Hour()%6 == 0 {
+			return 0.8
+		}
+		return 0
+	case "pop":
+		return 40 + 30*cycle
+	case "windspeedms", "ws_10min":
+		return 4 + 2*cycle
+	case "windgust", "wg_10min":
+		return 7 + 3*cycle
+	case "winddirection", "wd_10min":
+		return 225
+	case "humidity", "rh":
+		return 80 - 15*cycle
+	case "totalcloudcover", "n_man":
+		return 75
+	case "weathersymbol3":
+		if t.Hour()%6 == 0 {
+			return 31
+		}
+		return 2
+	}
+	return 0
+}
+
+// writeFMIException writes an OWS ExceptionReport, which FMI returns with
+// status 400 for bad requests and server-side failures alike
+func writeFMIException(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", "text/xml; charset=UTF-8")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<ExceptionReport xmlns="http://www.opengis.net/ows/1.1" version="2.0.0" xml:lang="eng">
+  <Exception exceptionCode="OperationProcessingFailed">
+    <ExceptionText>%s</ExceptionText>
+  </Exception>
+</ExceptionReport>
+`, text)
+}
@@ -0,0 +1,188 @@
+// Package mockapi serves synthetic versions of the Digitransit, FMI (weather
+// and warnings) and spot-hinta.fi APIs, so the dashboard can be developed
+// without network or API keys and edge cases can be reproduced on demand.
+package mockapi
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Paths served by the mock, relative to its base URL
+const (
+	RoutingPath   = "/routing/v2/hsl/gtfs/v1"
+	GeocodingPath = "/geocoding/v1/search"
+	FMIPath       = "/wfs"
+	SpotPath      = "/spot"
+	WarningsPath  = "/cap/feed"
+)
+
+// Scenarios that can be combined, e.g. "empty,slow"
+var Scenarios = map[string]string{
+	"normal":    "plausible data for every request",
+	"empty":     "no departures, alerts, itineraries, weather members, warnings or prices",
+	"errors":    "every API returns 500 (FMI returns an ExceptionReport)",
+	"ratelimit": "every API returns 429 with Retry-After",
+	"auth":      "Digitransit rejects the API key with 401",
+	"nan":       "FMI values are NaN every few hours, departures lack realtime data",
+	"slow":      "every response is delayed by the configured delay",
+	"dst":       "clocks go back at the next midnight (repeated hour in prices, GTFS service day not at midnight)",
+}
+
+// Server is the mock upstream. Change its scenario at runtime with
+// GET /scenario?set=empty,slow; GET / lists the scenarios.
+type Server struct {
+	mu        sync.RWMutex
+	scenarios []string
+	delay     time.Duration
+	failRate  float64 // Fraction of requests answered with 503
+}
+
+// New creates a server with the given comma-separated scenarios
+func New(scenarios string, delay time.Duration, failRate float64) (*Server, error) {
+	s := &Server{delay: delay, failRate: failRate}
+	if err := s.setScenarios(scenarios); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// URLs returns the dashboard config overrides that point at a mock served at base
+func URLs(base string) map[string]string {
+	base = strings.TrimSuffix(base, "/")
+	return map[string]string{
+		"hsl_api_url":       base + RoutingPath,
+		"geocoding_api_url": base + GeocodingPath,
+		"fmi_api_url":       base + FMIPath,
+		"spot_api_url":      base + SpotPath,
+		"warnings_api_url":  base + WarningsPath,
+	}
+}
+
+func (s *Server) setScenarios(list string) error {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := Scenarios[name]; !ok {
+			return fmt.Errorf("unknown scenario %q", name)
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		names = []string{"normal"}
+	}
+	s.mu.Lock()
+	s.scenarios = names
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Server) has(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Contains(s.scenarios, name)
+}
+
+// Handler returns the HTTP handler for all mock APIs
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handleIndex)
+	mux.HandleFunc("/scenario", s.handleScenario)
+	mux.Handle(RoutingPath, s.wrap("digitransit", s.handleGraphQL))
+	mux.Handle(GeocodingPath, s.wrap("digitransit", s.handleGeocoding))
+	mux.Handle(FMIPath, s.wrap("fmi", s.handleFMI))
+	mux.Handle(SpotPath, s.wrap("spot", s.handleSpot))
+	mux.Handle(WarningsPath, s.wrap("warnings", s.handleWarnings))
+	return mux
+}
+
+// wrap applies the delay and failure scenarios shared by all APIs
+func (s *Server) wrap(api string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("Mock: %s %s", r.Method, r.URL)
+		if s.has("slow") && s.delay > 0 {
+			select {
+			case <-time.After(s.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case s.has("ratelimit"):
+			w.Header().Set("Retry-After", "5")
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		case s.has("auth") && api == "digitransit":
+			http.Error(w, "Access denied due to invalid subscription key", http.StatusUnauthorized)
+		case s.has("errors") && api == "fmi":
+			writeFMIException(w, "Internal error while processing the request.")
+		case s.has("errors"):
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		case s.failRate > 0 && rand.Float64() < s.failRate:
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		default:
+			h(w, r)
+		}
+	})
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.RLock()
+	active := strings.Join(s.scenarios, ",")
+	s.mu.RUnlock()
+
+	names := make([]string, 0, len(Scenarios))
+	for name := range Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "Active scenario: %s\n\nSwitch with /scenario?set=name[,name...]\n\n", active)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, Scenarios[name])
+	}
+}
+
+func (s *Server) handleScenario(w http.ResponseWriter, r *http.Request) {
+	if set := r.URL.Query().Get("set"); set != "" {
+		if err := s.setScenarios(set); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Mock: scenario set to %s", set)
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fmt.Fprintln(w, strings.Join(s.scenarios, ","))
+}
+
+// zoneFor returns the zone to format t in. In the dst scenario times from
+// the next local midnight on use an offset one hour lower, as after the
+// clocks go back, so one wall-clock hour appears twice.
+func (s *Server) zoneFor(t time.Time) *time.Location {
+	now := time.Now()
+	if !s.has("dst") {
+		return now.Location()
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+	if t.Before(midnight) {
+		return now.Location()
+	}
+	_, offset := midnight.Zone()
+	return time.FixedZone("DST", offset-3600)
+}
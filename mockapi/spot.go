@@ -0,0 +1,46 @@
+package mockapi
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+)
+
+// handleSpot serves 15 minute prices for today and tomorrow in the
+// spot-hinta.fi format, with the usual morning and evening peaks
+func (s *Server) handleSpot(w http.ResponseWriter, r *http.Request) {
+	type price struct {
+		DateTime     string  `json:"DateTime"`
+		PriceNoTax   float64 `json:"PriceNoTax"`
+		PriceWithTax float64 `json:"PriceWithTax"`
+	}
+
+	prices := []price{}
+	if !s.has("empty") {
+		now := time.Now()
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		end := start.AddDate(0, 0, 2)
+		for t := start; t.Before(end); t = t.Add(15 * time.Minute) {
+			hour := float64(t.Hour()) + float64(t.Minute())/60
+			noTax := 0.03 +
+				0.05*math.Exp(-math.Pow(hour-8, 2)/4) + // Morning peak
+				0.08*math.Exp(-math.Pow(hour-18, 2)/6) // Evening peak
+			if t.Hour() < 5 {
+				noTax -= 0.035 // Cheap night, occasionally negative
+			}
+			prices = append(prices, price{
+				DateTime:     t.In(s.zoneFor(t)).Format(time.RFC3339),
+				PriceNoTax:   round(noTax),
+				PriceWithTax: round(noTax * 1.255),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prices)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100000) / 100000
+}
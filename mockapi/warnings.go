@@ -0,0 +1,72 @@
+package mockapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// handleWarnings serves an Atom feed of CAP alerts like FMI's warning feed:
+// a wind warning covering all of southern Finland and a forest fire warning
+// for Lapland, both valid for the next 12 hours
+func (s *Server) handleWarnings(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UTC().Truncate(time.Hour)
+	var entries []string
+	if !s.has("empty") {
+		entries = append(entries,
+			capEntry("wind", "Moderate", now, "Uusimaa",
+				"59.5,19.0 62.0,19.0 62.0,31.5 59.5,31.5 59.5,19.0",
+				"Tuulivaroitus maa-alueille", "Keltainen tuulivaroitus: Uusimaa", "Puuskat 20 m/s.",
+				"Wind warning for land areas", "Yellow wind warning: Uusimaa", "Gusts of 20 m/s."),
+			capEntry("fire", "Severe", now, "Lapland",
+				"66.0,23.0 69.0,23.0 69.0,29.0 66.0,29.0 66.0,23.0",
+				"Metsäpalovaroitus", "Metsäpalovaroitus: Lappi", "Maasto on erittäin kuivaa.",
+				"Forest fire warning", "Forest fire warning: Lapland", "The terrain is very dry."),
+		)
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml")
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>mock-warnings</id>
+  <title>Mock FMI warnings</title>
+  <updated>%s</updated>
+%s</feed>
+`, now.Format(time.RFC3339), strings.Join(entries, ""))
+}
+
+// capEntry returns a feed entry with an embedded CAP alert in Finnish and
+// English
+func capEntry(id, severity string, onset time.Time, area, polygon string, fiEvent, fiHeadline, fiText, enEvent, enHeadline, enText string) string {
+	identifier := "mock." + id + "." + onset.Format("20060102T15")
+	info := func(lang, event, headline, text string) string {
+		return fmt.Sprintf(`        <info>
+          <language>%s</language>
+          <category>Met</category>
+          <event>%s</event>
+          <severity>%s</severity>
+          <onset>%s</onset>
+          <expires>%s</expires>
+          <headline>%s</headline>
+          <description>%s</description>
+          <area><areaDesc>%s</areaDesc><polygon>%s</polygon></area>
+        </info>
+`, lang, event, severity, onset.Format(time.RFC3339), onset.Add(12*time.Hour).Format(time.RFC3339), headline, text, area, polygon)
+	}
+	return fmt.Sprintf(`  <entry>
+    <id>urn:oid:%s</id>
+    <title>%s</title>
+    <updated>%s</updated>
+    <content type="text/xml">
+      <alert xmlns="urn:oasis:names:tc:emergency:cap:1.2">
+        <identifier>%s</identifier>
+        <sent>%s</sent>
+        <status>Actual</status>
+        <msgType>Alert</msgType>
+%s%s      </alert>
+    </content>
+  </entry>
+`, identifier, enHeadline, onset.Format(time.RFC3339), identifier, onset.Format(time.RFC3339),
+		info("fi-FI", fiEvent, fiHeadline, fiText), info("en-GB", enEvent, enHeadline, enText))
+}
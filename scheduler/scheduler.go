@@ -0,0 +1,388 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"rasp_info/fetcher"
+	"sync"
+	"time"
+)
+
+// Defaults for retry behaviour after a failed fetch
+const (
+	DefaultMinBackoff = 15 * time.Second
+	DefaultJitter     = 0.2 // +/- 20% of the computed delay
+	DefaultTimeout    = 30 * time.Second
+)
+
+// Health describes the current state of a scheduled source
+type Health struct {
+	Name                string    `json:"name"`
+	Interval            string    `json:"interval"`
+	Running             bool      `json:"running"`
+	Waiting             bool      `json:"waiting"` // Needs another source's data first
+	LastRun             time.Time `json:"last_run"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorAt         time.Time `json:"last_error_at"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	NextRun             time.Time `json:"next_run"`
+}
+
+// Timing decides when a source runs. config.Schedule implements it for
+// time-of-day rules; Every is a plain fixed interval.
+type Timing interface {
+	// Interval returns the regular interval in effect at now
+	Interval(now time.Time) time.Duration
+	// Adjust returns the run time to use instead of next, e.g. to skip a
+	// paused window. now is the time of the previous run.
+	Adjust(now, next time.Time) time.Time
+}
+
+// NextRunSetter is implemented by fetchers that want to know when their next
+// regular run is, e.g. so their data does not look stale during a pause. It
+// is called after every successful fetch.
+type NextRunSetter interface {
+	SetNextRun(t time.Time)
+}
+
+// Every is a Timing with a fixed interval and no pauses
+type Every time.Duration
+
+func (e Every) Interval(time.Time) time.Duration   { return time.Duration(e) }
+func (e Every) Adjust(_, next time.Time) time.Time { return next }
+
+// Daily is a Timing that runs once a day, the given offset after local
+// midnight. Retries after a failure are not delayed to the next day.
+type Daily time.Duration
+
+func (d Daily) Interval(time.Time) time.Duration { return 24 * time.Hour }
+
+func (d Daily) Adjust(now, next time.Time) time.Time {
+	y, m, day := now.Date()
+	run := time.Date(y, m, day+1, 0, 0, 0, 0, now.Location()).Add(time.Duration(d))
+	if next.After(run) {
+		return run
+	}
+	return next
+}
+
+type job struct {
+	fetcher fetcher.Fetcher
+	timing  Timing
+	health  Health
+	trigger chan struct{}
+	retime  chan struct{} // Timing changed; recompute the next run
+
+	// Set by record: when the last run finished and whether the next run
+	// is a retry, which a new timing must not postpone
+	lastDone time.Time
+	retrying bool
+}
+
+// Scheduler owns all fetchers and runs each of them on its own interval,
+// retrying failed fetches with exponential backoff and jitter. A fetcher
+// returning fetcher.ErrNotReady is not failing; it is retried after
+// MinBackoff, or as soon as another source succeeds.
+type Scheduler struct {
+	MinBackoff time.Duration
+	Jitter     float64
+	Timeout    time.Duration // Deadline for a single fetch
+
+	mu      sync.RWMutex
+	jobs    []*job
+	started bool
+
+	stop        chan struct{}
+	fetchCancel context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		MinBackoff: DefaultMinBackoff,
+		Jitter:     DefaultJitter,
+		Timeout:    DefaultTimeout,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Add registers a fetcher to be run according to timing. Must be called before Start.
+func (s *Scheduler) Add(name string, f fetcher.Fetcher, timing Timing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &job{
+		fetcher: f,
+		timing:  timing,
+		trigger: make(chan struct{}, 1),
+		retime:  make(chan struct{}, 1),
+		health: Health{
+			Name:     name,
+			Interval: timing.Interval(time.Now()).String(),
+		},
+	})
+}
+
+// Start launches one goroutine per registered fetcher. Each fetcher runs
+// immediately, or when its timing's pause ends, and then on its interval.
+// Cancelling ctx stops new fetches but lets running ones finish; only Stop
+// cancels them, once its deadline passes.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	s.fetchCancel = cancel
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.run(ctx, fetchCtx, j)
+	}
+}
+
+// Stop stops scheduling new fetches and waits for in-flight ones to finish.
+// If ctx expires first, the remaining fetches are cancelled.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.started {
+		s.mu.Unlock()
+		return nil
+	}
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	s.fetchCancel()
+	<-done
+	return err
+}
+
+// SetTiming changes when a source runs. The pending run is rescheduled as if
+// timing had been in effect since the last run, so a shorter interval or a
+// new paused window applies right away.
+func (s *Scheduler) SetTiming(name string, timing Timing) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j := s.find(name)
+	if j == nil {
+		return
+	}
+	j.timing = timing
+	j.health.Interval = timing.Interval(time.Now()).String()
+	select {
+	case j.retime <- struct{}{}:
+	default:
+	}
+}
+
+// Trigger runs a source as soon as possible instead of waiting for its timer.
+// It is a no-op if a triggered run is already pending.
+func (s *Scheduler) Trigger(name string) {
+	s.mu.RLock()
+	j := s.find(name)
+	s.mu.RUnlock()
+	if j == nil {
+		return
+	}
+	select {
+	case j.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// find returns the job registered under name. Callers must hold s.mu.
+func (s *Scheduler) find(name string) *job {
+	for _, j := range s.jobs {
+		if j.health.Name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// Health returns a snapshot of every source's state, in registration order
+func (s *Scheduler) Health() []Health {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Health, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		out = append(out, j.health)
+	}
+	return out
+}
+
+// run fetches j until ctx is cancelled or the scheduler stops. Fetches use
+// fetchCtx so that a shutdown can drain them.
+func (s *Scheduler) run(ctx, fetchCtx context.Context, j *job) {
+	defer s.wg.Done()
+	now := time.Now()
+	s.mu.RLock()
+	first := j.timing.Adjust(now, now) // Don't start inside a paused window
+	s.mu.RUnlock()
+	timer := time.NewTimer(time.Until(first))
+	defer timer.Stop()
+	s.setNextRun(j, first)
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-j.trigger:
+			timer.Stop() // No stale fire to drain since Go 1.23
+		case <-j.retime:
+			next, regular := s.reschedule(j)
+			if n, ok := j.fetcher.(NextRunSetter); ok && regular {
+				n.SetNextRun(next)
+			}
+			timer.Reset(time.Until(next))
+			continue
+		}
+
+		s.setRunning(j)
+		err := s.fetch(fetchCtx, j)
+		if fetchCtx.Err() != nil {
+			// Shutting down; a cancelled fetch is not a source failure
+			return
+		}
+		next := s.record(j, err)
+		s.setNextRun(j, next)
+		if n, ok := j.fetcher.(NextRunSetter); ok && err == nil {
+			n.SetNextRun(next)
+		}
+		timer.Reset(time.Until(next))
+	}
+}
+
+// reschedule recomputes the next run of j after its timing changed. It
+// reports whether that is a regular run after a success, as opposed to the
+// first run or a retry.
+func (s *Scheduler) reschedule(j *job) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	var next time.Time
+	regular := false
+	switch {
+	case j.lastDone.IsZero():
+		next = j.timing.Adjust(now, now) // Not run yet
+	case j.retrying:
+		next = j.timing.Adjust(j.lastDone, j.health.NextRun)
+	default:
+		next = j.timing.Adjust(j.lastDone, j.lastDone.Add(j.timing.Interval(j.lastDone)))
+		regular = true
+	}
+	j.health.NextRun = next
+	return next, regular
+}
+
+func (s *Scheduler) fetch(ctx context.Context, j *job) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	return j.fetcher.Fetch(ctx)
+}
+
+func (s *Scheduler) setRunning(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.health.Running = true
+	j.health.LastRun = time.Now()
+}
+
+func (s *Scheduler) setNextRun(j *job, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.health.NextRun = t
+}
+
+// record updates health after a fetch and returns the time of the next run
+func (s *Scheduler) record(j *job, err error) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	interval := j.timing.Interval(now)
+	j.health.Running = false
+	j.health.Interval = interval.String()
+	j.lastDone = now
+	j.retrying = err != nil
+
+	if errors.Is(err, fetcher.ErrNotReady) {
+		j.health.Waiting = true
+		log.Printf("Scheduler: %s %v", j.health.Name, err)
+		return j.timing.Adjust(now, now.Add(s.MinBackoff))
+	}
+	j.health.Waiting = false
+
+	if err == nil {
+		j.health.LastSuccess = now
+		j.health.ConsecutiveFailures = 0
+		s.wakeWaiting()
+		return j.timing.Adjust(now, now.Add(interval))
+	}
+
+	j.health.ConsecutiveFailures++
+	j.health.LastError = err.Error()
+	j.health.LastErrorAt = now
+	next := j.timing.Adjust(now, now.Add(s.backoff(j.health.ConsecutiveFailures, interval)))
+	log.Printf("Scheduler: %s failed (%d in a row), retrying in %s: %v",
+		j.health.Name, j.health.ConsecutiveFailures, next.Sub(now).Round(time.Second), err)
+	return next
+}
+
+// wakeWaiting triggers the sources waiting for another one's data. Callers
+// must hold s.mu.
+func (s *Scheduler) wakeWaiting() {
+	for _, j := range s.jobs {
+		if j.health.Waiting {
+			select {
+			case j.trigger <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// backoff returns MinBackoff doubled for every consecutive failure, capped at
+// the regular interval and randomized by the configured jitter.
+func (s *Scheduler) backoff(failures int, interval time.Duration) time.Duration {
+	delay := s.MinBackoff
+	for i := 1; i < failures && delay < interval; i++ {
+		delay *= 2
+	}
+	if delay > interval {
+		delay = interval
+	}
+	if s.Jitter > 0 {
+		spread := float64(delay) * s.Jitter
+		delay += time.Duration((rand.Float64()*2 - 1) * spread)
+	}
+	if delay <= 0 {
+		delay = s.MinBackoff
+	}
+	return delay
+}
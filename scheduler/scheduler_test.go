@@ -0,0 +1,287 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"rasp_info/config"
+	"rasp_info/fetcher"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fetchFunc adapts a function to fetcher.Fetcher
+type fetchFunc func(ctx context.Context) error
+
+func (f fetchFunc) Fetch(ctx context.Context) error { return f(ctx) }
+
+// pausedUntil is a Timing that runs hourly but not before the given time
+type pausedUntil time.Time
+
+func (p pausedUntil) Interval(time.Time) time.Duration { return time.Hour }
+
+func (p pausedUntil) Adjust(_, next time.Time) time.Time {
+	if until := time.Time(p); next.Before(until) {
+		return until
+	}
+	return next
+}
+
+// nextRunFetcher reports when it runs and the next run it is told about
+type nextRunFetcher struct {
+	ran  chan time.Time
+	next chan time.Time
+}
+
+func (f *nextRunFetcher) Fetch(context.Context) error { f.ran <- time.Now(); return nil }
+func (f *nextRunFetcher) SetNextRun(t time.Time)      { f.next <- t }
+
+func TestStartWaitsForPausedWindow(t *testing.T) {
+	until := time.Now().Add(200 * time.Millisecond)
+	f := &nextRunFetcher{ran: make(chan time.Time, 1), next: make(chan time.Time, 1)}
+	s := New()
+	s.Add("HSL", f, pausedUntil(until))
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	var ranAt time.Time
+	select {
+	case ranAt = <-f.ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fetch did not run after the pause")
+	}
+	if ranAt.Before(until) {
+		t.Errorf("first fetch ran %s before the pause ended", until.Sub(ranAt))
+	}
+	if next := <-f.next; next.Sub(ranAt) < time.Hour || next.Sub(ranAt) > time.Hour+time.Second {
+		t.Errorf("next run passed to the fetcher = %s after the fetch, want the 1h interval", next.Sub(ranAt))
+	}
+}
+
+func TestStartOutOfSeasonRunsOnce(t *testing.T) {
+	// A one-day season half a year away
+	day := time.Now().AddDate(0, 6, 0).Format("01-02")
+	timing := config.SeasonalSchedule{Schedule: config.Schedule{Base: time.Minute}, Start: day, End: day}
+	f := &nextRunFetcher{ran: make(chan time.Time, 1), next: make(chan time.Time, 1)}
+	s := New()
+	s.Add("CityBike", f, timing)
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+
+	// The first run reports the season as over instead of waiting for it
+	select {
+	case <-f.ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("first fetch did not run out of season")
+	}
+	if next := <-f.next; next.Format("01-02") != day || next.Sub(time.Now()) < 24*time.Hour {
+		t.Errorf("next run = %s, want the start of the season on %s", next, day)
+	}
+}
+
+func TestSetTimingShortensPendingRun(t *testing.T) {
+	ran := make(chan time.Time, 10)
+	s := New()
+	s.Add("HSL", fetchFunc(func(context.Context) error { ran <- time.Now(); return nil }), Every(time.Hour))
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	<-ran
+
+	// A reload shortens the interval; the run armed for an hour from now moves
+	s.SetTiming("HSL", Every(50*time.Millisecond))
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("shortened interval did not take effect before the old timer")
+	}
+	if h := s.Health()[0]; h.Interval != "50ms" {
+		t.Errorf("interval = %s, want 50ms", h.Interval)
+	}
+}
+
+func TestSetTimingAppliesNewPause(t *testing.T) {
+	ran := make(chan time.Time, 10)
+	s := New()
+	s.Add("HSL", fetchFunc(func(context.Context) error { ran <- time.Now(); return nil }),
+		config.Schedule{Base: 300 * time.Millisecond})
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	<-ran
+
+	// A reload adds a night pause covering now; the armed run must not happen
+	now := time.Now()
+	pause := config.ScheduleRule{From: now.Add(-time.Minute).Format("15:04"), To: now.Add(2 * time.Hour).Format("15:04"), Pause: true}
+	s.SetTiming("HSL", config.Schedule{Base: 300 * time.Millisecond, Rules: []config.ScheduleRule{pause}})
+	select {
+	case at := <-ran:
+		t.Fatalf("fetch ran at %s inside the new paused window", at.Format("15:04:05.000"))
+	case <-time.After(time.Second):
+	}
+	if next := s.Health()[0].NextRun; next.Before(now.Add(time.Hour)) {
+		t.Errorf("next run = %s, want the end of the pause", next)
+	}
+}
+
+func TestStopDrainsRunningFetch(t *testing.T) {
+	tests := []struct {
+		name      string
+		fetchTime time.Duration
+		deadline  time.Duration
+		wantErr   bool // Stop gave up and cancelled the fetch
+	}{
+		{"finishes within deadline", 50 * time.Millisecond, time.Second, false},
+		{"cancelled after deadline", time.Minute, 50 * time.Millisecond, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started := make(chan struct{})
+			result := make(chan error, 1)
+			s := New()
+			s.Add("slow", fetchFunc(func(ctx context.Context) error {
+				close(started)
+				select {
+				case <-time.After(tt.fetchTime):
+					result <- nil
+				case <-ctx.Done():
+					result <- ctx.Err()
+				}
+				return nil
+			}), Every(time.Hour))
+
+			// Cancelling the start context is what SIGTERM does in main
+			ctx, cancel := context.WithCancel(context.Background())
+			s.Start(ctx)
+			<-started
+			cancel()
+
+			stopCtx, stopCancel := context.WithTimeout(context.Background(), tt.deadline)
+			defer stopCancel()
+			err := s.Stop(stopCtx)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Stop() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if fetchErr := <-result; (fetchErr != nil) != tt.wantErr {
+				t.Errorf("fetch ended with %v, want cancelled %v", fetchErr, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWaitingSourceRunsAfterOtherSucceeds(t *testing.T) {
+	var weatherDone atomic.Bool
+	warningsDone := make(chan struct{})
+	s := New()
+	s.MinBackoff = time.Hour // Only a wake-up can rerun the waiting source
+	s.Add("Warnings", fetchFunc(func(context.Context) error {
+		if !weatherDone.Load() {
+			return fmt.Errorf("%w: no coordinates yet", fetcher.ErrNotReady)
+		}
+		close(warningsDone)
+		return nil
+	}), Every(time.Hour))
+	s.Add("FMI", fetchFunc(func(context.Context) error {
+		time.Sleep(50 * time.Millisecond) // Let Warnings find it not ready first
+		weatherDone.Store(true)
+		return nil
+	}), Every(time.Hour))
+
+	s.Start(context.Background())
+	defer s.Stop(context.Background())
+	select {
+	case <-warningsDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting source was not rerun after the other succeeded")
+	}
+
+	h := s.Health()[0]
+	for deadline := time.Now().Add(time.Second); h.LastSuccess.IsZero() && time.Now().Before(deadline); h = s.Health()[0] {
+		time.Sleep(5 * time.Millisecond) // Wait for the run to be recorded
+	}
+	if h.Waiting || h.LastError != "" || h.ConsecutiveFailures != 0 || h.LastSuccess.IsZero() {
+		t.Errorf("health = %+v, want a success and no failures", h)
+	}
+}
+
+func TestDailyAdjust(t *testing.T) {
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC) }
+	daily := Daily(time.Minute)
+
+	tests := []struct {
+		name      string
+		now, next time.Time
+		want      time.Time
+	}{
+		{name: "regular run moves to after midnight", now: at(16, 10, 0), next: at(17, 10, 0), want: at(17, 0, 1)},
+		{name: "retry today is kept", now: at(16, 10, 0), next: at(16, 10, 15), want: at(16, 10, 15)},
+		{name: "retry past midnight is clamped", now: at(16, 23, 59), next: at(17, 0, 30), want: at(17, 0, 1)},
+		{name: "run just after midnight", now: at(17, 0, 1), next: at(18, 0, 1), want: at(18, 0, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := daily.Adjust(tt.now, tt.next); !got.Equal(tt.want) {
+				t.Errorf("Adjust() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	if got := daily.Interval(at(16, 10, 0)); got != 24*time.Hour {
+		t.Errorf("Interval() = %s, want 24h", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	s := New()
+	s.Jitter = 0
+
+	tests := []struct {
+		name     string
+		failures int
+		interval time.Duration
+		want     time.Duration
+	}{
+		{name: "first failure", failures: 1, interval: time.Hour, want: 15 * time.Second},
+		{name: "doubles", failures: 3, interval: time.Hour, want: time.Minute},
+		{name: "capped at interval", failures: 10, interval: 5 * time.Minute, want: 5 * time.Minute},
+		{name: "interval below minimum", failures: 1, interval: 10 * time.Second, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.backoff(tt.failures, tt.interval); got != tt.want {
+				t.Errorf("backoff(%d, %s) = %s, want %s", tt.failures, tt.interval, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("jitter", func(t *testing.T) {
+		s := New() // Default 20% jitter
+		for range 100 {
+			if got := s.backoff(3, time.Hour); got < 48*time.Second || got > 72*time.Second {
+				t.Fatalf("backoff = %s, want 1m +/- 20%%", got)
+			}
+		}
+	})
+}
+
+func TestFailuresAreCountedAndReset(t *testing.T) {
+	s := New()
+	j := &job{timing: Every(time.Hour), health: Health{Name: "FMI"}}
+
+	before := time.Now()
+	for i := 1; i <= 3; i++ {
+		next := s.record(j, fmt.Errorf("timeout %d", i))
+		if j.health.ConsecutiveFailures != i || j.health.LastError != fmt.Sprintf("timeout %d", i) {
+			t.Fatalf("after failure %d: health = %+v", i, j.health)
+		}
+		if next.After(before.Add(time.Hour + time.Minute)) {
+			t.Errorf("retry at %s is later than the interval", next)
+		}
+	}
+
+	next := s.record(j, nil)
+	if j.health.ConsecutiveFailures != 0 || j.health.LastSuccess.IsZero() {
+		t.Errorf("after success: health = %+v", j.health)
+	}
+	if d := next.Sub(j.health.LastSuccess); d != time.Hour {
+		t.Errorf("next run %s after success, want the 1h interval", d)
+	}
+}
@@ -47,18 +47,35 @@ if [ "$config_opt" == "1" ] || [ "$config_opt" == "2" ]; then
     # HSL API Key
     read -p "Enter HSL API Key (leave empty to skip/use existing if manual): " hsl_key
 
-    # Weather Location
-    echo -e "\nSelect Weather Location:"
-    cities=("Espoo" "Helsinki" "Turku" "Tampere" "Oulu" "Rovaniemi")
-    select city in "${cities[@]}"; do
-        if [ -n "$city" ]; then
-            weather_loc="$city"
+    # Weather Locations
+    echo -e "\nConfigure Weather Locations (up to 4, the first one gets the forecast graph):"
+    echo "Note: Give each a place name (e.g. Espoo), coordinates (e.g. 61.05,28.19) or an FMI station id (e.g. 100971)."
+    weather_json=""
+    weather_count=0
+
+    while [ $weather_count -lt 4 ]; do
+        read -p "Enter Location Name (e.g., Home) or 'done' to finish: " loc_name
+        if [ "$loc_name" == "done" ] || [ -z "$loc_name" ]; then
             break
+        fi
+        read -p "Place, lat,lon or station id for $loc_name [$loc_name]: " loc_value
+        if [ -z "$loc_value" ]; then loc_value="$loc_name"; fi
+
+        if [[ "$loc_value" =~ ^[0-9]+$ ]]; then
+            loc_key="fmisid"
+        elif [[ "$loc_value" =~ ^-?[0-9.]+,\ *-?[0-9.]+$ ]]; then
+            loc_key="latlon"
+            loc_value="${loc_value// /}"
         else
-            echo "Invalid selection"
+            loc_key="place"
         fi
+
+        if [ -n "$weather_json" ]; then weather_json="$weather_json,"; fi
+        weather_json="$weather_json {\"name\": \"$loc_name\", \"$loc_key\": \"$loc_value\"}"
+        weather_count=$((weather_count + 1))
+        echo -e "${GREEN}Location added.${NC}"
     done
-    if [ -z "$weather_loc" ]; then weather_loc="Espoo"; fi
+    if [ -z "$weather_json" ]; then weather_json=' {"name": "Espoo", "place": "Espoo"}'; fi
 
     # Bus Stops
     echo -e "\nConfigure Bus Stops (up to 6 stops):"
@@ -86,7 +103,7 @@ if [ "$config_opt" == "1" ] || [ "$config_opt" == "2" ]; then
   "hsl_api_key": "$hsl_key",
   "fmi_api_url": "https://opendata.fmi.fi/wfs",
   "spot_api_url": "https://api.spot-hinta.fi/TodayAndDayForward?region=FI&priceResolution=15",
-  "weather_location": "$weather_loc",
+  "weather_locations": [ $weather_json ],
   "bus_stops": [ $stops_json ]
 }
 EOF
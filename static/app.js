@@ -3,6 +3,7 @@ const ENABLE_TUNING_CONTROLS = false;
 let elecChart = null;
 let weatherChart = null;
 let latestData = null;
+let eventsConnected = false;
 let graphFontSize = 16;
 let busFontScale = 1.5;
 
@@ -41,9 +42,14 @@ function renderDashboard(data) {
         return;
     }
 
+    markStale('electricity', data.electricity);
+    markStale('transport', data.transport);
+    markStale('weather', data.weather);
+
     // Electricity
     const currentPrice = data.electricity.current_price;
     document.getElementById('elec-current').innerText = currentPrice ? currentPrice.toFixed(2) : "--";
+    renderPriceStats(document.getElementById('elec-stats'), data.electricity);
 
     // Graph: 15-min resolution
     if (data.electricity.prices) {
@@ -117,6 +123,9 @@ function renderDashboard(data) {
         // Transport
         const busList = document.getElementById('bus-list');
         busList.innerHTML = '';
+        renderAlerts(busList, data.alerts);
+        renderJourneys(busList, data.journeys);
+        renderBikes(busList, data.bikes);
         if (data.transport.stops) {
             data.transport.stops.forEach(stop => {
                 const group = document.createElement('div');
@@ -137,10 +146,28 @@ function renderDashboard(data) {
                         if (diffMins < 0) timeDisplay = "Now";
 
                         const timeClass = dep.realtime ? 'realtime' : 'scheduled';
+                        if (dep.cancelled || dep.skipped) {
+                            div.classList.add('cancelled');
+                        }
+
+                        const delayMins = Math.round((dep.delay_seconds || 0) / 60);
+                        let delayDisplay = '';
+                        if (dep.cancelled) {
+                            delayDisplay = 'Cancelled';
+                        } else if (dep.skipped) {
+                            delayDisplay = 'Skips stop';
+                        } else if (dep.realtime && delayMins >= 1) {
+                            delayDisplay = `+${delayMins} min`;
+                        } else if (dep.realtime && delayMins <= -1) {
+                            delayDisplay = `${delayMins} min`;
+                        }
+                        const platform = dep.platform ? `<span class="bus-platform">${dep.platform}</span>` : '';
 
                         div.innerHTML = `
                             <span class="bus-route">${dep.route_number}</span>
                             <span class="bus-dest">${dep.destination}</span>
+                            ${platform}
+                            <span class="bus-delay">${delayDisplay}</span>
                             <span class="bus-time ${timeClass}">${timeDisplay}</span>
                         `;
                         group.appendChild(div);
@@ -152,21 +179,47 @@ function renderDashboard(data) {
             });
         }
 
-        // Weather
-        if (data.weather.current) {
-            document.getElementById('weather-temp').innerText = data.weather.current.temperature.toFixed(1);
-            document.getElementById('weather-symbol').innerText = data.weather.current.symbol;
-            if (data.weather.current.pop > 0) {
-                document.getElementById('weather-pop').innerText = data.weather.current.pop.toFixed(0) + "%";
+        // Weather: the first location gets the graph, others a line each
+        const locations = data.weather.locations || [];
+        const home = locations[0];
+        if (home && home.current) {
+            document.getElementById('weather-temp').innerText = formatValue(home.current.temperature, 1);
+            document.getElementById('weather-symbol').innerText = symbolIcon(home.current.symbol);
+            document.getElementById('weather-symbol').title = home.current.symbol;
+            document.getElementById('weather-wind').innerText = formatWind(home.current);
+            const obs = home.current_source === 'observation' ? home.observation : null;
+            document.getElementById('weather-station').innerText = obs
+                ? `${obs.station} ${formatClock(new Date(obs.time))}`
+                : (locations.length > 1 ? home.name : '');
+            if (home.current.pop > 0) {
+                document.getElementById('weather-pop').innerText = home.current.pop.toFixed(0) + "%";
             } else {
                 document.getElementById('weather-pop').innerText = "";
             }
         }
 
+        const otherLocations = document.getElementById('weather-locations');
+        otherLocations.innerHTML = '';
+        locations.slice(1).forEach(loc => {
+            const div = document.createElement('div');
+            div.className = 'weather-location' + (loc.error ? ' stale' : '');
+            div.title = loc.error || '';
+            div.innerHTML = `
+                <span class="location-name">${loc.name}</span>
+                <span class="location-symbol">${symbolIcon(loc.current.symbol)}</span>
+                <span class="location-temp">${formatValue(loc.current.temperature, 1)} °C</span>
+                <span class="wind-value">${formatWind(loc.current)}</span>
+            `;
+            otherLocations.appendChild(div);
+        });
+
+        renderAstronomy(document.getElementById('weather-astronomy'), data.astronomy);
+        renderWarnings(document.getElementById('weather-warnings'), data.warnings);
+
         const weatherCanvas = document.getElementById('weather-graph');
-        if (weatherCanvas && data.weather.forecast) {
+        if (weatherCanvas && home && home.forecast) {
             const now = new Date();
-            const forecast = data.weather.forecast
+            const forecast = home.forecast
                 .filter(wp => new Date(wp.time) >= now)
                 .sort((a, b) => new Date(a.time) - new Date(b.time))
                 .slice(0, 24);
@@ -286,6 +339,199 @@ function renderDashboard(data) {
         }
 }
 
+// Show service alerts above the departures, most severe first
+function renderAlerts(container, alertsData) {
+    if (!alertsData || !alertsData.alerts) {
+        return;
+    }
+    alertsData.alerts.forEach(alert => {
+        const text = alert.header.fi || alert.header.en || Object.values(alert.header)[0];
+        if (!text) {
+            return;
+        }
+        const div = document.createElement('div');
+        div.className = `transport-alert severity-${(alert.severity || '').toLowerCase()}`;
+        const routes = alert.routes && alert.routes.length ? `${alert.routes.join(', ')}: ` : '';
+        div.textContent = routes + text;
+        container.appendChild(div);
+    });
+}
+
+// Show today's price range and the cheapest time to run each appliance
+function renderPriceStats(container, electricity) {
+    container.innerHTML = '';
+    const days = electricity.days || [];
+    const today = days[0];
+    if (today) {
+        const div = document.createElement('div');
+        div.textContent = `${today.min.toFixed(1)}–${today.max.toFixed(1)} · avg ${today.average.toFixed(1)}`;
+        container.appendChild(div);
+    }
+    (electricity.windows || []).forEach(win => {
+        const div = document.createElement('div');
+        div.className = 'price-window';
+        div.textContent = `${win.name} ${formatClock(new Date(win.start))}–${formatClock(new Date(win.end))} · ${win.average.toFixed(1)}`;
+        container.appendChild(div);
+    });
+}
+
+// Show sunrise, sunset, day length and its change since yesterday, and the moon
+function renderAstronomy(container, astro) {
+    if (!astro || !astro.date) {
+        container.textContent = '';
+        return;
+    }
+    let sun;
+    if (astro.polar_day) {
+        sun = '☀️ midnight sun';
+    } else if (astro.polar_night) {
+        sun = '☀️ polar night';
+    } else {
+        const time = t => {
+            const d = new Date(t);
+            return d.getFullYear() > 1 ? formatClock(d) : '--'; // Zero time: not today
+        };
+        sun = `☀️ ${time(astro.sunrise)}–${time(astro.sunset)}`;
+    }
+    const minutes = Math.round(astro.day_length / 60);
+    const change = Math.round(astro.day_length_change / 60);
+    let length = `${Math.floor(minutes / 60)} h ${minutes % 60} min`;
+    if (change !== 0) {
+        length += ` (${change > 0 ? '+' : '−'}${Math.abs(change)} min)`;
+    }
+    const moon = MOON_ICONS[astro.moon_phase_name] || '';
+    container.textContent = `${sun} · ${length} · ${moon} ${astro.moon_illumination.toFixed(0)} %`;
+}
+
+// Show FMI weather warnings for the configured area, most severe first
+function renderWarnings(container, warningsData) {
+    container.innerHTML = '';
+    if (!warningsData || !warningsData.warnings) {
+        return;
+    }
+    warningsData.warnings.forEach(warning => {
+        const headline = warning.headline || {};
+        const text = headline.fi || headline.en || Object.values(headline)[0];
+        if (!text) {
+            return;
+        }
+        const div = document.createElement('div');
+        div.className = `weather-warning severity-${(warning.severity || '').toLowerCase()}`;
+        const validTo = new Date(warning.valid_to);
+        const until = validTo.getFullYear() > 1 ? ` (→ ${formatClock(validTo)})` : ''; // Zero time: no end
+        div.textContent = text + until;
+        const description = warning.description || {};
+        div.title = description.fi || description.en || '';
+        container.appendChild(div);
+    });
+}
+
+// Show the next itinerary of each saved trip: when to leave and how
+function renderJourneys(container, journeys) {
+    if (!journeys || !journeys.trips) {
+        return;
+    }
+    const now = new Date();
+    journeys.trips.forEach(trip => {
+        const next = (trip.itineraries || []).find(it => new Date(it.start) >= now);
+        if (!next) {
+            return;
+        }
+        const routes = next.legs
+            .filter(leg => leg.route)
+            .map(leg => leg.route)
+            .join(' → ');
+        const div = document.createElement('div');
+        div.className = 'journey-item';
+        div.innerHTML = `
+            <span class="journey-name">${trip.name}</span>
+            <span class="journey-routes">${routes || 'walk'}</span>
+            <span class="journey-time">${formatClock(new Date(next.start))} → ${formatClock(new Date(next.end))}</span>
+        `;
+        container.appendChild(div);
+    });
+}
+
+// City bike availability, hidden outside the season
+function renderBikes(container, bikes) {
+    if (!bikes || !bikes.in_season || !bikes.stations) {
+        return;
+    }
+    bikes.stations.forEach(station => {
+        const div = document.createElement('div');
+        div.className = 'bike-item';
+        if (!station.operative) {
+            div.classList.add('closed');
+        }
+        const status = station.operative
+            ? `${station.bikes_available} bikes · ${station.spaces_available} docks`
+            : 'Closed';
+        div.innerHTML = `
+            <span class="bike-name">${station.name}</span>
+            <span class="bike-status">${status}</span>
+        `;
+        container.appendChild(div);
+    });
+}
+
+// Icons for the symbols documented in fetcher/weather.go
+const WEATHER_ICONS = {
+    'clear-day': '☀️', 'clear-night': '🌙',
+    'partly-cloudy-day': '⛅', 'partly-cloudy-night': '☁️',
+    'cloudy': '☁️',
+    'showers-day': '🌦️', 'showers-night': '🌧️',
+    'light-rain': '🌧️', 'rain': '🌧️', 'heavy-rain': '🌧️',
+    'snow-showers-day': '🌨️', 'snow-showers-night': '🌨️',
+    'light-snow': '🌨️', 'snow': '❄️', 'heavy-snow': '❄️',
+    'thunder': '⛈️', 'sleet': '🌨️', 'fog': '🌫️'
+};
+
+const MOON_ICONS = {
+    'new': '🌑', 'waxing-crescent': '🌒', 'first-quarter': '🌓', 'waxing-gibbous': '🌔',
+    'full': '🌕', 'waning-gibbous': '🌖', 'last-quarter': '🌗', 'waning-crescent': '🌘'
+};
+
+function symbolIcon(symbol) {
+    return WEATHER_ICONS[symbol] || symbol || '';
+}
+
+// Values missing from FMI's data are left out of the JSON
+function formatValue(value, digits) {
+    return value == null ? '–' : value.toFixed(digits);
+}
+
+function formatWind(point) {
+    if (!point.wind_speed) {
+        return '';
+    }
+    // Arrow points where the wind blows to
+    const arrows = ['↓', '↙', '←', '↖', '↑', '↗', '→', '↘'];
+    const arrow = arrows[Math.round((point.wind_direction || 0) / 45) % 8];
+    let text = `${arrow} ${point.wind_speed.toFixed(0)}`;
+    if (point.wind_gust != null && point.wind_gust > point.wind_speed + 1) {
+        text += ` (${point.wind_gust.toFixed(0)})`;
+    }
+    text += ' m/s';
+    if (point.feels_like != null && point.temperature != null && Math.abs(point.feels_like - point.temperature) >= 1) {
+        text += ` · feels ${point.feels_like.toFixed(0)}°`;
+    }
+    return text;
+}
+
+function formatClock(d) {
+    return d.getHours().toString().padStart(2, '0') + ":" + d.getMinutes().toString().padStart(2, '0');
+}
+
+// Dim a panel whose data is outdated or restored from a snapshot
+function markStale(elementId, section) {
+    const el = document.getElementById(elementId);
+    if (!el || !section) {
+        return;
+    }
+    el.classList.toggle('stale', !!section.stale);
+    el.title = section.fetched_at ? `Updated ${new Date(section.fetched_at).toLocaleString('fi-FI')}` : '';
+}
+
 function updateClock() {
     const now = new Date();
     document.getElementById('clock').innerText = now.toLocaleTimeString('fi-FI', { hour: '2-digit', minute: '2-digit', second: '2-digit' });
@@ -297,10 +543,39 @@ updateClock();
 
 initControls();
 
-// Update every minute
-setInterval(update, 60000);
+// Live updates via SSE; polling is only a fallback while the stream is down
+connectEvents();
+setInterval(() => {
+    if (!eventsConnected) {
+        update();
+    }
+}, 60000);
 update(); // Initial call
 
+// Re-render cached data so departure countdowns keep moving between updates
+setInterval(refreshWithCachedData, 30000);
+
+function connectEvents() {
+    if (!window.EventSource) {
+        return;
+    }
+    const source = new EventSource('/api/events');
+    source.onopen = () => { eventsConnected = true; };
+    source.onerror = () => { eventsConnected = false; }; // EventSource reconnects by itself
+    ['weather', 'transport', 'electricity', 'alerts', 'warnings', 'astronomy', 'journeys', 'bikes'].forEach(section => {
+        source.addEventListener(section, (e) => {
+            try {
+                latestData = { ...(latestData || {}), [section]: JSON.parse(e.data) };
+                if (latestData.weather && latestData.transport && latestData.electricity) {
+                    renderDashboard(latestData);
+                }
+            } catch (err) {
+                console.error("Event handling failed", err);
+            }
+        });
+    });
+}
+
 function getPriceColor(price) {
     if (price == null) {
         return 'rgba(255, 255, 255, 0.2)';
@@ -0,0 +1,64 @@
+package store
+
+import "time"
+
+// eventBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it
+const eventBuffer = 16
+
+// Event is a section update pushed to subscribers
+type Event struct {
+	ID      uint64 // Monotonic, unique for the lifetime of the process
+	Section string // One of the Section* constants
+	Data    any    // The section value, e.g. WeatherData
+}
+
+// Subscribe returns a channel receiving an event for every section update.
+// The channel is primed with the current state of every section so a new
+// subscriber does not have to wait for the next fetch; each primed event
+// gets its own ID, like any update. Call cancel to
+// unsubscribe; the channel is closed afterwards.
+func (s *Store) Subscribe() (<-chan Event, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch := make(chan Event, eventBuffer)
+	if s.subs == nil {
+		s.subs = make(map[chan Event]struct{})
+	}
+	s.subs[ch] = struct{}{}
+
+	now := time.Now()
+	d := s.current(now)
+	for _, section := range sections {
+		s.eventID++
+		ch <- Event{ID: s.eventID, Section: section, Data: d.value(section)}
+	}
+
+	cancel := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+// publish notifies subscribers of a section change. Callers must hold s.mu.
+// Slow subscribers miss events rather than blocking updates.
+func (s *Store) publish(section string) {
+	s.eventID++
+	if len(s.subs) == 0 {
+		return
+	}
+	d := s.current(time.Now())
+	ev := Event{ID: s.eventID, Section: section, Data: d.value(section)}
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribe(t *testing.T) {
+	s := New()
+	s.UpdateWeather(WeatherData{Locations: []LocationWeather{{Name: "Home"}}})
+
+	events, cancel := s.Subscribe()
+
+	// Primed with every section, fetched or not, each with its own ID
+	primed := make(map[string]Event)
+	var lastID uint64
+	for range sections {
+		ev := <-events
+		if ev.ID <= lastID {
+			t.Errorf("primed %s with id %d after id %d", ev.Section, ev.ID, lastID)
+		}
+		lastID = ev.ID
+		primed[ev.Section] = ev
+	}
+	if len(primed) != len(sections) {
+		t.Fatalf("primed sections = %d, want %d", len(primed), len(sections))
+	}
+	if w, ok := primed[SectionWeather].Data.(WeatherData); !ok || len(w.Locations) != 1 || w.Locations[0].Name != "Home" {
+		t.Errorf("primed weather = %+v", primed[SectionWeather].Data)
+	}
+
+	s.UpdateTransport(TransportData{Stops: []StopData{{StopName: "Koti"}}})
+	select {
+	case ev := <-events:
+		tr, ok := ev.Data.(TransportData)
+		if ev.Section != SectionTransport || !ok || len(tr.Stops) != 1 {
+			t.Errorf("update event = %+v", ev)
+		}
+		if ev.ID <= lastID {
+			t.Errorf("event id %d does not follow the primed id %d", ev.ID, lastID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no event for the update")
+	}
+
+	// A second subscriber continues the same sequence
+	others, cancelOthers := s.Subscribe()
+	if ev := <-others; ev.ID <= lastID+1 {
+		t.Errorf("second subscriber primed with id %d, want after %d", ev.ID, lastID+1)
+	}
+	cancelOthers()
+
+	cancel()
+	if _, ok := <-events; ok {
+		t.Error("channel still open after cancel")
+	}
+	cancel() // Cancelling twice is harmless
+	s.UpdateTransport(TransportData{})
+}
@@ -0,0 +1,94 @@
+package store
+
+// Section names, used to address per-section metadata and events
+const (
+	SectionWeather     = "weather"
+	SectionTransport   = "transport"
+	SectionElectricity = "electricity"
+	SectionAlerts      = "alerts"
+	SectionJourneys    = "journeys"
+	SectionBikes       = "bikes"
+	SectionWarnings    = "warnings"
+	SectionAstronomy   = "astronomy"
+)
+
+// sections lists every data section, in the order they are sent to new
+// event subscribers
+var sections = []string{
+	SectionWeather,
+	SectionTransport,
+	SectionElectricity,
+	SectionAlerts,
+	SectionJourneys,
+	SectionBikes,
+	SectionWarnings,
+	SectionAstronomy,
+}
+
+// meta returns a pointer to a section's metadata, or nil for unknown sections
+func (d *Data) meta(section string) *SectionMeta {
+	switch section {
+	case SectionWeather:
+		return &d.Weather.SectionMeta
+	case SectionTransport:
+		return &d.Transport.SectionMeta
+	case SectionElectricity:
+		return &d.Electricity.SectionMeta
+	case SectionAlerts:
+		return &d.Alerts.SectionMeta
+	case SectionJourneys:
+		return &d.Journeys.SectionMeta
+	case SectionBikes:
+		return &d.Bikes.SectionMeta
+	case SectionWarnings:
+		return &d.Warnings.SectionMeta
+	case SectionAstronomy:
+		return &d.Astronomy.SectionMeta
+	}
+	return nil
+}
+
+// value returns a section by name, or nil for unknown sections
+func (d *Data) value(section string) any {
+	switch section {
+	case SectionWeather:
+		return d.Weather
+	case SectionTransport:
+		return d.Transport
+	case SectionElectricity:
+		return d.Electricity
+	case SectionAlerts:
+		return d.Alerts
+	case SectionJourneys:
+		return d.Journeys
+	case SectionBikes:
+		return d.Bikes
+	case SectionWarnings:
+		return d.Warnings
+	case SectionAstronomy:
+		return d.Astronomy
+	}
+	return nil
+}
+
+// copySection copies one section from src to dst
+func copySection(dst, src *Data, section string) {
+	switch section {
+	case SectionWeather:
+		dst.Weather = src.Weather
+	case SectionTransport:
+		dst.Transport = src.Transport
+	case SectionElectricity:
+		dst.Electricity = src.Electricity
+	case SectionAlerts:
+		dst.Alerts = src.Alerts
+	case SectionJourneys:
+		dst.Journeys = src.Journeys
+	case SectionBikes:
+		dst.Bikes = src.Bikes
+	case SectionWarnings:
+		dst.Warnings = src.Warnings
+	case SectionAstronomy:
+		dst.Astronomy = src.Astronomy
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is bumped when the on-disk format changes incompatibly
+const snapshotVersion = 4
+
+// Snapshot is the on-disk representation of the fetched data sections.
+// Debug data is not persisted (it is excluded from Data's JSON).
+type Snapshot struct {
+	Version int       `json:"version"`
+	SavedAt time.Time `json:"saved_at"`
+	Data    Data      `json:"data"`
+}
+
+// SaveSnapshot atomically writes the current data sections to path.
+// The file is written to a temporary sibling first and renamed into place,
+// so a power cut never leaves a half-written snapshot behind.
+func (s *Store) SaveSnapshot(path string) error {
+	s.mu.RLock()
+	snap := Snapshot{
+		Version: snapshotVersion,
+		SavedAt: time.Now(),
+		Data:    s.data,
+	}
+	rev := s.rev
+	s.mu.RUnlock()
+
+	b, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op after a successful rename
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	s.mu.Lock()
+	s.savedRev = rev
+	s.mu.Unlock()
+	return nil
+}
+
+// LoadSnapshot restores data sections from path. Restored sections are
+// marked stale until a fresh fetch replaces them. Sections that were already
+// updated by a fetcher are left untouched.
+func (s *Store) LoadSnapshot(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var snap Snapshot
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, section := range sections {
+		if !s.data.meta(section).FetchedAt.IsZero() || snap.Data.meta(section).FetchedAt.IsZero() {
+			continue
+		}
+		copySection(&s.data, &snap.Data, section)
+		s.data.meta(section).Restored = true
+	}
+	s.savedRev = s.rev
+	return nil
+}
+
+// RunSnapshots saves a snapshot to path every interval if data has changed
+// since the last save. It returns when ctx is cancelled.
+func (s *Store) RunSnapshots(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.SaveIfChanged(path)
+		}
+	}
+}
+
+// SaveIfChanged writes a snapshot only if data changed since the last save
+func (s *Store) SaveIfChanged(path string) {
+	s.mu.RLock()
+	dirty := s.rev != s.savedRev
+	s.mu.RUnlock()
+	if !dirty {
+		return
+	}
+	if err := s.SaveSnapshot(path); err != nil {
+		log.Printf("Error saving snapshot: %v", err)
+	}
+}
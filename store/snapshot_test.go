@@ -0,0 +1,138 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// savedStore returns a snapshot path holding weather and transport data
+func savedStore(t *testing.T) string {
+	t.Helper()
+	src := New()
+	src.UpdateWeather(WeatherData{
+		SectionMeta: SectionMeta{Source: "fmi"},
+		Locations:   []LocationWeather{{Name: "Home", Lat: 60.2, Lon: 24.66}},
+	})
+	src.UpdateTransport(TransportData{
+		SectionMeta: SectionMeta{Source: "hsl"},
+		Stops:       []StopData{{StopName: "Koti", StopCode: "E2185"}},
+	})
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	if err := src.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	path := savedStore(t)
+
+	dst := New()
+	dst.SetInterval(SectionWeather, time.Hour)
+	if err := dst.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	d := dst.Get()
+
+	if len(d.Weather.Locations) != 1 || d.Weather.Locations[0].Name != "Home" || d.Weather.Source != "fmi" {
+		t.Errorf("weather = %+v", d.Weather)
+	}
+	if len(d.Transport.Stops) != 1 || d.Transport.Stops[0].StopCode != "E2185" {
+		t.Errorf("transport = %+v", d.Transport)
+	}
+	for _, m := range []SectionMeta{d.Weather.SectionMeta, d.Transport.SectionMeta} {
+		if !m.Restored || !m.Stale || m.FetchedAt.IsZero() {
+			t.Errorf("restored section meta = %+v, want restored, stale and fetched", m)
+		}
+	}
+	if d.Electricity.Restored || !d.Electricity.FetchedAt.IsZero() {
+		t.Errorf("section missing from the snapshot was restored: %+v", d.Electricity.SectionMeta)
+	}
+
+	// A fresh fetch replaces restored data
+	dst.UpdateWeather(WeatherData{Locations: []LocationWeather{{Name: "Cabin"}}})
+	if w := dst.Get().Weather; w.Restored || w.Stale {
+		t.Errorf("fetched section meta = %+v, want fresh", w.SectionMeta)
+	}
+}
+
+func TestLoadSnapshotKeepsFetchedSections(t *testing.T) {
+	path := savedStore(t)
+
+	dst := New()
+	dst.UpdateWeather(WeatherData{Locations: []LocationWeather{{Name: "Cabin"}}})
+	if err := dst.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+	d := dst.Get()
+	if len(d.Weather.Locations) != 1 || d.Weather.Locations[0].Name != "Cabin" || d.Weather.Restored {
+		t.Errorf("weather = %+v, want the fetched data kept", d.Weather)
+	}
+	if !d.Transport.Restored {
+		t.Errorf("transport should be restored")
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	oldVersion, err := json.Marshal(Snapshot{Version: snapshotVersion - 1, SavedAt: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		notExist bool
+	}{
+		{name: "missing file", path: filepath.Join(dir, "missing.json"), notExist: true},
+		{name: "corrupt file", path: write("corrupt.json", []byte(`{"version":`))},
+		{name: "old version", path: write("old.json", oldVersion)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := New().LoadSnapshot(tt.path)
+			if err == nil {
+				t.Fatal("LoadSnapshot() succeeded")
+			}
+			if os.IsNotExist(err) != tt.notExist {
+				t.Errorf("error = %v, want not-exist %v", err, tt.notExist)
+			}
+		})
+	}
+}
+
+func TestSaveIfChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	s := New()
+
+	s.SaveIfChanged(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged store was saved (%v)", err)
+	}
+
+	s.UpdateBikes(BikesData{})
+	s.SaveIfChanged(path)
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("changed store was not saved: %v", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	s.SaveIfChanged(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("store saved again without changes (%v)", err)
+	}
+}
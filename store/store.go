@@ -5,52 +5,247 @@ import (
 	"time"
 )
 
-// WeatherDataPoint holds a single point of weather info
+// SectionMeta describes where a data section came from and how fresh it is.
+// It is embedded in every section so the fields appear inline in JSON.
+type SectionMeta struct {
+	Source    string    `json:"source"`
+	FetchedAt time.Time `json:"fetched_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	LastError string    `json:"last_error,omitempty"` // Most recent failed fetch, cleared on success
+	Restored  bool      `json:"restored,omitempty"`   // Loaded from snapshot, not yet refreshed
+	Stale     bool      `json:"stale"`                // Computed on read
+}
+
+// WeatherDataPoint holds a single point of weather info. Values missing from
+// the forecast are nil.
 type WeatherDataPoint struct {
-	Temperature   float64   `json:"temperature"`
-	WindSpeed     float64   `json:"wind_speed"`
-	Precipitation float64   `json:"precipitation"`
-	Pop           float64   `json:"pop"` // Probability of Precipitation
-	Symbol        string    `json:"symbol"`
+	Temperature   *float64  `json:"temperature,omitempty"`
+	FeelsLike     *float64  `json:"feels_like,omitempty"`
+	WindSpeed     *float64  `json:"wind_speed,omitempty"`     // m/s
+	WindGust      *float64  `json:"wind_gust,omitempty"`      // m/s
+	WindDirection *float64  `json:"wind_direction,omitempty"` // Degrees the wind blows from, 0 = north
+	Humidity      *float64  `json:"humidity,omitempty"`       // Relative, %
+	CloudCover    *float64  `json:"cloud_cover,omitempty"`    // %
+	Precipitation *float64  `json:"precipitation,omitempty"`
+	Pop           *float64  `json:"pop,omitempty"`         // Probability of Precipitation
+	Symbol        string    `json:"symbol"`                // See fetcher.WeatherSymbol
+	SymbolCode    int       `json:"symbol_code,omitempty"` // FMI WeatherSymbol3
+	Time          time.Time `json:"time"`
+}
+
+// WeatherObservation is the latest measurement at an FMI weather station.
+// Values the station does not measure or did not report are nil.
+type WeatherObservation struct {
+	Station       string    `json:"station"`
+	StationID     string    `json:"station_id,omitempty"` // FMI fmisid
 	Time          time.Time `json:"time"`
+	Temperature   *float64  `json:"temperature,omitempty"`
+	WindSpeed     *float64  `json:"wind_speed,omitempty"`
+	WindGust      *float64  `json:"wind_gust,omitempty"`
+	WindDirection *float64  `json:"wind_direction,omitempty"`
+	Humidity      *float64  `json:"humidity,omitempty"`
+	Precipitation *float64  `json:"precipitation,omitempty"` // Last hour, mm
 }
 
-// WeatherData holds current and forecast
+// LocationWeather holds current conditions and forecast for one
+// configured weather location
+type LocationWeather struct {
+	Name          string              `json:"name"`
+	Lat           float64             `json:"lat,omitempty"` // Forecast point, as resolved by FMI
+	Lon           float64             `json:"lon,omitempty"`
+	Current       WeatherDataPoint    `json:"current"`
+	CurrentSource string              `json:"current_source"` // "observation" or "forecast"
+	Observation   *WeatherObservation `json:"observation,omitempty"`
+	Forecast      []WeatherDataPoint  `json:"forecast"`
+	Error         string              `json:"error,omitempty"` // Last fetch failed; the data is from an earlier one
+}
+
+// WeatherData holds the weather of each location, in config order
 type WeatherData struct {
-	Current  WeatherDataPoint   `json:"current"`
-	Forecast []WeatherDataPoint `json:"forecast"`
+	SectionMeta
+	Locations []LocationWeather `json:"locations"`
 }
 
 // StopData holds info for a specific stop
 type StopData struct {
 	StopName   string      `json:"stop_name"`
+	StopCode   string      `json:"stop_code,omitempty"` // e.g. E2185
 	Departures []Departure `json:"departures"`
 }
 
 // TransportData holds list of stops
 type TransportData struct {
+	SectionMeta
 	Stops     []StopData `json:"stops"`
 	Timestamp time.Time  `json:"timestamp"`
 }
 
 type Departure struct {
-	RouteNumber string    `json:"route_number"`
-	Destination string    `json:"destination"`
-	Time        time.Time `json:"time"`
-	Realtime    bool      `json:"realtime"`
+	RouteNumber   string    `json:"route_number"`
+	Destination   string    `json:"destination"`
+	Time          time.Time `json:"time"` // Realtime estimate if available, else scheduled
+	ScheduledTime time.Time `json:"scheduled_time"`
+	DelaySeconds  int       `json:"delay_seconds"` // Negative if early
+	Realtime      bool      `json:"realtime"`
+	RealtimeState string    `json:"realtime_state,omitempty"`
+	Cancelled     bool      `json:"cancelled"`
+	Skipped       bool      `json:"skipped"` // Trip runs but does not stop here
+	Platform      string    `json:"platform,omitempty"`
+	StopCode      string    `json:"stop_code,omitempty"` // Stop served, may be a platform of a station
+	Mode          string    `json:"mode,omitempty"`      // bus, tram, metro, train or ferry
 }
 
 // ElectricityData holds current and future prices
 type ElectricityData struct {
-	CurrentPrice float64     `json:"current_price"` // c/kWh
-	Prices       []PriceInfo `json:"prices"`        // Next 24h or so
-	Timestamp    time.Time   `json:"timestamp"`
+	SectionMeta
+	CurrentPrice float64           `json:"current_price"` // c/kWh
+	Prices       []PriceInfo       `json:"prices"`        // Next 24h or so
+	Days         []DailyPriceStats `json:"days"`          // Each day prices are known for
+	Windows      []PriceWindow     `json:"windows"`       // Cheapest window per configured appliance, computed on read
+	Timestamp    time.Time         `json:"timestamp"`
 }
 
 type PriceInfo struct {
-	Price     float64   `json:"price"`
-	StartTime time.Time `json:"start_time"`
-	EndTime   time.Time `json:"end_time"`
+	Price      float64   `json:"price"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	Percentile int       `json:"percentile"` // Rank among the day's prices, 0 cheapest to 100
+	Band       string    `json:"band"`       // Percentile band, see fetcher.PriceBand
+}
+
+// DailyPriceStats summarizes one local day's prices
+type DailyPriceStats struct {
+	Date    string    `json:"date"` // "2006-01-02"
+	Min     float64   `json:"min"`  // c/kWh
+	Max     float64   `json:"max"`
+	Average float64   `json:"average"`
+	MinTime time.Time `json:"min_time"` // Start of the cheapest slot
+	MaxTime time.Time `json:"max_time"`
+	Slots   int       `json:"slots"` // Fewer than 96 if the day is only partly known
+}
+
+// PriceWindow is the cheapest time to run something for Duration
+type PriceWindow struct {
+	Name     string    `json:"name,omitempty"` // Appliance
+	Duration int       `json:"duration"`       // Seconds
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Average  float64   `json:"average"` // c/kWh over the window
+}
+
+// Alert is a service disruption notice, e.g. a diverted route or closed stop
+type Alert struct {
+	ID          string            `json:"id"`
+	Severity    string            `json:"severity"`    // INFO, WARNING, SEVERE or UNKNOWN_SEVERITY
+	Header      map[string]string `json:"header"`      // Language code -> text
+	Description map[string]string `json:"description"` // Language code -> text
+	URL         string            `json:"url,omitempty"`
+	ValidFrom   time.Time         `json:"valid_from"`
+	ValidTo     time.Time         `json:"valid_to"`
+	Stops       []string          `json:"stops,omitempty"`  // Affected configured stops, by name
+	Routes      []string          `json:"routes,omitempty"` // Affected route short names
+}
+
+// AlertsData holds alerts for the configured stops and their routes
+type AlertsData struct {
+	SectionMeta
+	Alerts []Alert `json:"alerts"`
+}
+
+// Leg is one part of an itinerary, e.g. a walk or a bus ride
+type Leg struct {
+	Mode     string    `json:"mode"`            // walk, bus, tram, metro, train, ferry, ...
+	Route    string    `json:"route,omitempty"` // Route short name for transit legs
+	Headsign string    `json:"headsign,omitempty"`
+	From     string    `json:"from"`
+	To       string    `json:"to"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Realtime bool      `json:"realtime"`
+	Distance float64   `json:"distance"` // Meters
+}
+
+// Itinerary is one way to make a trip
+type Itinerary struct {
+	Start        time.Time `json:"start"` // When to leave the origin
+	End          time.Time `json:"end"`
+	Duration     int       `json:"duration"` // Seconds
+	Transfers    int       `json:"transfers"`
+	WalkDistance float64   `json:"walk_distance"` // Meters
+	Legs         []Leg     `json:"legs"`
+}
+
+// TripPlan holds the planned itineraries for one saved trip
+type TripPlan struct {
+	Name        string      `json:"name"`
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	ArriveBy    time.Time   `json:"arrive_by,omitempty"` // Zero for depart-now trips
+	Itineraries []Itinerary `json:"itineraries"`
+}
+
+// JourneysData holds plans for all saved trips
+type JourneysData struct {
+	SectionMeta
+	Trips []TripPlan `json:"trips"`
+}
+
+// BikeStationStatus is the availability at one city bike station
+type BikeStationStatus struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	BikesAvailable  int    `json:"bikes_available"`
+	SpacesAvailable int    `json:"spaces_available"` // Free docks
+	Operative       bool   `json:"operative"`
+	Realtime        bool   `json:"realtime"`
+}
+
+// BikesData holds city bike availability for the configured stations
+type BikesData struct {
+	SectionMeta
+	InSeason bool                `json:"in_season"` // False in winter when stations are closed
+	Stations []BikeStationStatus `json:"stations"`
+}
+
+// WeatherWarning is an FMI warning, e.g. for wind, frost or slippery roads
+type WeatherWarning struct {
+	ID          string            `json:"id"`
+	Type        map[string]string `json:"type"`        // Language code -> warning type, e.g. "Wind warning"
+	Severity    string            `json:"severity"`    // CAP severity: Minor, Moderate, Severe or Extreme
+	Headline    map[string]string `json:"headline"`    // Language code -> text
+	Description map[string]string `json:"description"` // Language code -> text
+	Areas       []string          `json:"areas"`       // Affected areas that matched the configured region
+	URL         string            `json:"url,omitempty"`
+	ValidFrom   time.Time         `json:"valid_from"`
+	ValidTo     time.Time         `json:"valid_to"`
+}
+
+// WarningsData holds the weather warnings for the configured region, most
+// severe first
+type WarningsData struct {
+	SectionMeta
+	Warnings []WeatherWarning `json:"warnings"`
+}
+
+// AstronomyData holds today's sun and moon at the first weather location.
+// Sun times are zero on days the event does not happen.
+type AstronomyData struct {
+	SectionMeta
+	Location         string    `json:"location"`
+	Lat              float64   `json:"lat"`
+	Lon              float64   `json:"lon"`
+	Date             string    `json:"date"` // Local day, "2006-01-02"
+	Sunrise          time.Time `json:"sunrise"`
+	Sunset           time.Time `json:"sunset"`
+	Dawn             time.Time `json:"dawn"` // Civil twilight
+	Dusk             time.Time `json:"dusk"`
+	DayLength        int       `json:"day_length"`        // Seconds
+	DayLengthChange  int       `json:"day_length_change"` // Seconds compared to yesterday
+	PolarDay         bool      `json:"polar_day,omitempty"`
+	PolarNight       bool      `json:"polar_night,omitempty"`
+	MoonPhase        float64   `json:"moon_phase"`        // 0 new, 0.5 full
+	MoonIllumination float64   `json:"moon_illumination"` // %
+	MoonPhaseName    string    `json:"moon_phase_name"`   // See astro.PhaseNew etc.
 }
 
 // Data is the aggregate state
@@ -58,6 +253,11 @@ type Data struct {
 	Weather     WeatherData     `json:"weather"`
 	Transport   TransportData   `json:"transport"`
 	Electricity ElectricityData `json:"electricity"`
+	Alerts      AlertsData      `json:"alerts"`
+	Journeys    JourneysData    `json:"journeys"`
+	Bikes       BikesData       `json:"bikes"`
+	Warnings    WarningsData    `json:"warnings"`
+	Astronomy   AstronomyData   `json:"astronomy"`
 	APICalls    []APICallLog    `json:"-"` // Don't expose in main status
 	AppLogs     []LogEntry      `json:"-"` // Don't expose in main status
 	Device      DeviceInfo      `json:"-"` // Don't expose in main status
@@ -67,34 +267,189 @@ type Data struct {
 type Store struct {
 	mu   sync.RWMutex
 	data Data
+
+	// Expected refresh interval and next scheduled fetch per section, used
+	// to compute staleness
+	intervals map[string]time.Duration
+	nextRuns  map[string]time.Time
+
+	// rev counts section updates; savedRev is the rev last written to disk
+	rev      uint64
+	savedRev uint64
+
+	// Live update subscribers, see Subscribe
+	subs    map[chan Event]struct{}
+	eventID uint64
+
+	// Computes the appliance windows on read, see SetWindowFunc
+	windows WindowFunc
 }
 
+// WindowFunc returns the cheapest appliance windows among prices as of now
+type WindowFunc func(prices []PriceInfo, now time.Time) []PriceWindow
+
 func New() *Store {
-	return &Store{}
+	return &Store{intervals: make(map[string]time.Duration), nextRuns: make(map[string]time.Time)}
 }
 
+// Get returns a copy of the data with freshness computed as of now
 func (s *Store) Get() Data {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.data
+	return s.current(time.Now())
+}
+
+// current returns a copy of the data with freshness and appliance windows
+// computed. Callers must hold s.mu.
+func (s *Store) current(now time.Time) Data {
+	d := s.data
+	for _, section := range sections {
+		m := d.meta(section)
+		*m = s.freshness(section, *m, now)
+	}
+	if s.windows != nil {
+		d.Electricity.Windows = s.windows(d.Electricity.Prices, now)
+	}
+	return d
+}
+
+// SetWindowFunc sets how appliance windows are found. They are computed on
+// every read, so a window never starts before the current price slot.
+func (s *Store) SetWindowFunc(fn WindowFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.windows = fn
+}
+
+// SetInterval tells the store how often a section is expected to be refreshed.
+// A section expires after two intervals, i.e. once a scheduled fetch is missed.
+func (s *Store) SetInterval(section string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.intervals[section] = d
+}
+
+// SetNextRun tells the store when a section is next scheduled to be fetched,
+// after a successful fetch. The section then expires an interval after that
+// run instead of two after the last one, so it does not turn stale while its
+// schedule is paused, e.g. overnight.
+func (s *Store) SetNextRun(section string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextRuns[section] = t
+}
+
+// SetError records a failed fetch for a section without touching its data
+func (s *Store) SetError(section string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta := s.data.meta(section)
+	if meta == nil {
+		return
+	}
+	if err == nil {
+		meta.LastError = ""
+	} else {
+		meta.LastError = err.Error()
+	}
+	s.publish(section)
+}
+
+// stamp prepares metadata for freshly fetched data
+func (s *Store) stamp(m SectionMeta) SectionMeta {
+	if m.FetchedAt.IsZero() {
+		m.FetchedAt = time.Now()
+	}
+	m.LastError = ""
+	m.Restored = false
+	return m
+}
+
+func (s *Store) freshness(section string, m SectionMeta, now time.Time) SectionMeta {
+	if m.FetchedAt.IsZero() {
+		return m
+	}
+	if interval := s.intervals[section]; interval > 0 {
+		m.ExpiresAt = m.FetchedAt.Add(2 * interval)
+		// A next run from before this fetch no longer applies
+		if next := s.nextRuns[section]; next.After(m.FetchedAt) {
+			m.ExpiresAt = next.Add(interval)
+		}
+	}
+	m.Stale = m.Restored || (!m.ExpiresAt.IsZero() && now.After(m.ExpiresAt))
+	return m
 }
 
 func (s *Store) UpdateWeather(w WeatherData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	w.SectionMeta = s.stamp(w.SectionMeta)
 	s.data.Weather = w
+	s.rev++
+	s.publish(SectionWeather)
 }
 
 func (s *Store) UpdateTransport(t TransportData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	t.SectionMeta = s.stamp(t.SectionMeta)
 	s.data.Transport = t
+	s.rev++
+	s.publish(SectionTransport)
 }
 
 func (s *Store) UpdateElectricity(e ElectricityData) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	e.SectionMeta = s.stamp(e.SectionMeta)
 	s.data.Electricity = e
+	s.rev++
+	s.publish(SectionElectricity)
+}
+
+func (s *Store) UpdateAlerts(a AlertsData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.SectionMeta = s.stamp(a.SectionMeta)
+	s.data.Alerts = a
+	s.rev++
+	s.publish(SectionAlerts)
+}
+
+func (s *Store) UpdateJourneys(j JourneysData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j.SectionMeta = s.stamp(j.SectionMeta)
+	s.data.Journeys = j
+	s.rev++
+	s.publish(SectionJourneys)
+}
+
+func (s *Store) UpdateBikes(b BikesData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b.SectionMeta = s.stamp(b.SectionMeta)
+	s.data.Bikes = b
+	s.rev++
+	s.publish(SectionBikes)
+}
+
+func (s *Store) UpdateWarnings(w WarningsData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.SectionMeta = s.stamp(w.SectionMeta)
+	s.data.Warnings = w
+	s.rev++
+	s.publish(SectionWarnings)
+}
+
+func (s *Store) UpdateAstronomy(a AstronomyData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a.SectionMeta = s.stamp(a.SectionMeta)
+	s.data.Astronomy = a
+	s.rev++
+	s.publish(SectionAstronomy)
 }
 
 // --- Debug / Monitoring ---
@@ -103,7 +458,7 @@ type APICallLog struct {
 	Timestamp time.Time `json:"timestamp"`
 	Duration  string    `json:"duration"`
 	URL       string    `json:"url"`
-	Status    string    `json:"status"` // "success" or "error"
+	Status    string    `json:"status"` // "success", "error", "waiting" or "cancelled"
 	Error     string    `json:"error,omitempty"`
 }
 
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFreshness(t *testing.T) {
+	fetched := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		interval    time.Duration
+		nextRun     time.Time // Set by the scheduler after a successful fetch
+		meta        SectionMeta
+		now         time.Time
+		wantExpires time.Time
+		wantStale   bool
+	}{
+		{name: "never fetched", interval: time.Minute, now: fetched},
+		{
+			name:        "fresh",
+			interval:    time.Minute,
+			meta:        SectionMeta{FetchedAt: fetched},
+			now:         fetched.Add(90 * time.Second),
+			wantExpires: fetched.Add(2 * time.Minute),
+		},
+		{
+			name:        "at expiry",
+			interval:    time.Minute,
+			meta:        SectionMeta{FetchedAt: fetched},
+			now:         fetched.Add(2 * time.Minute),
+			wantExpires: fetched.Add(2 * time.Minute),
+		},
+		{
+			name:        "expired",
+			interval:    time.Minute,
+			meta:        SectionMeta{FetchedAt: fetched},
+			now:         fetched.Add(2*time.Minute + time.Second),
+			wantExpires: fetched.Add(2 * time.Minute),
+			wantStale:   true,
+		},
+		{
+			name:        "restored is stale",
+			interval:    time.Hour,
+			meta:        SectionMeta{FetchedAt: fetched, Restored: true},
+			now:         fetched.Add(time.Minute),
+			wantExpires: fetched.Add(2 * time.Hour),
+			wantStale:   true,
+		},
+		{
+			name:        "paused overnight",
+			interval:    5 * time.Minute,
+			nextRun:     fetched.Add(6*time.Hour + 30*time.Minute),
+			meta:        SectionMeta{FetchedAt: fetched},
+			now:         fetched.Add(6 * time.Hour),
+			wantExpires: fetched.Add(6*time.Hour + 35*time.Minute),
+		},
+		{
+			name:        "missed the run after a pause",
+			interval:    5 * time.Minute,
+			nextRun:     fetched.Add(6*time.Hour + 30*time.Minute),
+			meta:        SectionMeta{FetchedAt: fetched},
+			now:         fetched.Add(6*time.Hour + 36*time.Minute),
+			wantExpires: fetched.Add(6*time.Hour + 35*time.Minute),
+			wantStale:   true,
+		},
+		{
+			name:        "next run from before the fetch is ignored",
+			interval:    time.Minute,
+			nextRun:     fetched.Add(-time.Minute),
+			meta:        SectionMeta{FetchedAt: fetched},
+			now:         fetched.Add(time.Minute),
+			wantExpires: fetched.Add(2 * time.Minute),
+		},
+		{
+			name: "no interval never expires",
+			meta: SectionMeta{FetchedAt: fetched},
+			now:  fetched.Add(24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New()
+			if tt.interval > 0 {
+				s.SetInterval(SectionWeather, tt.interval)
+			}
+			if !tt.nextRun.IsZero() {
+				s.SetNextRun(SectionWeather, tt.nextRun)
+			}
+			m := s.freshness(SectionWeather, tt.meta, tt.now)
+			if !m.ExpiresAt.Equal(tt.wantExpires) || m.Stale != tt.wantStale {
+				t.Errorf("expires %s, stale %v; want %s, %v", m.ExpiresAt, m.Stale, tt.wantExpires, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestSectionErrors(t *testing.T) {
+	s := New()
+	s.SetInterval(SectionWeather, time.Minute)
+
+	// A failure before the first fetch is reported without data
+	s.SetError(SectionWeather, errors.New("fmi down"))
+	if w := s.Get().Weather; w.LastError != "fmi down" || !w.FetchedAt.IsZero() {
+		t.Errorf("after first failure: %+v", w.SectionMeta)
+	}
+
+	s.UpdateWeather(WeatherData{SectionMeta: SectionMeta{Source: "fmi"}})
+	w := s.Get().Weather
+	if w.LastError != "" || w.FetchedAt.IsZero() || w.Stale {
+		t.Errorf("after success: %+v", w.SectionMeta)
+	}
+	fetched := w.FetchedAt
+
+	// A later failure keeps the data and its fetch time
+	s.SetError(SectionWeather, errors.New("timeout"))
+	w = s.Get().Weather
+	if w.LastError != "timeout" || !w.FetchedAt.Equal(fetched) || w.Source != "fmi" {
+		t.Errorf("after later failure: %+v", w.SectionMeta)
+	}
+
+	s.UpdateWeather(WeatherData{})
+	if w := s.Get().Weather; w.LastError != "" {
+		t.Errorf("error not cleared by the next success: %+v", w.SectionMeta)
+	}
+
+	// Clearing explicitly and unknown sections
+	s.SetError(SectionWeather, errors.New("again"))
+	s.SetError(SectionWeather, nil)
+	if w := s.Get().Weather; w.LastError != "" {
+		t.Errorf("SetError(nil) kept %q", w.LastError)
+	}
+	s.SetError("nonexistent", errors.New("ignored"))
+}
+
+func TestStampKeepsGivenFetchTime(t *testing.T) {
+	s := New()
+	at := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	m := s.stamp(SectionMeta{FetchedAt: at, LastError: "old", Restored: true})
+	if !m.FetchedAt.Equal(at) || m.LastError != "" || m.Restored {
+		t.Errorf("stamp() = %+v", m)
+	}
+	if m := s.stamp(SectionMeta{}); m.FetchedAt.IsZero() {
+		t.Error("stamp() left FetchedAt unset")
+	}
+}